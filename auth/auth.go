@@ -1,71 +1,98 @@
 package auth
 
 import (
+	"MidAI/paths"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
-	"runtime"
+	"strings"
 )
 
 // Config contains the Cloudflare account ID and API token.
 // These fields are necessary to authenticate requests to the Cloudflare API.
 type Config struct {
-	AccountID string `json:"account_id"`
-	Token     string `json:"token"`
+	AccountID string             `json:"account_id"`
+	Token     string             `json:"token"`
+	Personas  map[string]Persona `json:"personas,omitempty"` // Reusable named personas, keyed by name
+	Profile   string             `json:"-"`                  // Name of the profile the credentials belong to
+
+	AccountIDSource string `json:"-"` // Where the account ID was read from, e.g. an environment variable
+	TokenSource     string `json:"-"` // Where the token was read from, e.g. a token_command
+}
+
+// Persona bundles a system prompt with a preferred model and generation parameters,
+// so a behaviour such as "code-reviewer" or "translator" can be selected by name.
+type Persona struct {
+	SystemPrompt string          `json:"system_prompt"`    // System message sent ahead of the conversation
+	Model        string          `json:"model,omitempty"`  // Preferred model, used unless another one is requested
+	Params       json.RawMessage `json:"params,omitempty"` // Generation parameters, interpreted by the capability using the persona
 }
 
-var ConfigFile string // Path to the configuration file
-var homeDir string    // User's home directory path
-var err error         // Error variable for capturing function errors
+var ConfigFile string       // Path to the configuration file
+var LegacyConfigFile string // Path of the configuration file used by older versions
 
 // init is automatically called when the package is initialized.
-// It sets up the paths for the configuration file based on the OS.
+// It sets up the paths for the configuration file.
 func init() {
-	// Determine the user's home directory based on the operating system
-	if runtime.GOOS == "windows" {
-		homeDir = os.Getenv("USERPROFILE") // Get home directory on Windows
-	} else {
-		homeDir, err = os.UserHomeDir() // Get home directory on Unix-like systems
-		if err != nil {
-			panic(err) // Panic if unable to get home directory
-		}
-	}
+	// Set the config file path in the MidAI config directory
+	ConfigFile = filepath.Join(paths.ConfigDir, "config.json")
 
-	// Set the config file path in the user's home directory
-	ConfigFile = filepath.Join(homeDir, ".aiCFtoken.json")
+	// Older versions kept the configuration in the user's home directory
+	LegacyConfigFile = filepath.Join(paths.Home, ".aiCFtoken.json")
 }
 
-// LoadConfig loads the configuration from the user's home directory.
+// Migrate moves a configuration file written by an older version to its current
+// location. It is meant to be called once at startup, before the file is read.
+func Migrate() {
+	if moved, err := paths.MigrateLegacy(LegacyConfigFile, ConfigFile); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to migrate %s: %v\n", LegacyConfigFile, err)
+	} else if moved {
+		fmt.Fprintf(os.Stderr, "Moved configuration file %s to %s\n", LegacyConfigFile, ConfigFile)
+	}
+}
+
+// LoadConfig loads the configuration of the active profile. Credentials are resolved
+// from the CLOUDFLARE_ACCOUNT_ID and CLOUDFLARE_API_TOKEN environment variables, the
+// profile's token_command and the config file, in that order.
 // It returns a Config struct populated with the account ID and token, or an error.
 func LoadConfig() (Config, error) {
-	// Early check for initialization error
-	if err != nil {
-		fmt.Printf("To use this service you need a Cloudflare API Token with WorkerAI Read and Edit Access from this Address %s\n", "https://dash.cloudflare.com/profile/api-tokens")
-		return Config{}, err // Return error if initialization failed
+	// Read the config file, migrating a legacy single-profile file.
+	// A missing file is fine as long as the environment provides the credentials.
+	file, fileErr := LoadFile()
+	if fileErr != nil && !errors.Is(fileErr, ErrConfigNotExist) {
+		return Config{}, fileErr
 	}
 
-	// Read the config file
-	data, err := os.ReadFile(ConfigFile)
-	if err != nil {
-		// Return a specific error if the config file does not exist
-		if errors.Is(err, os.ErrNotExist) {
-			return Config{}, errors.New("config file does not exist")
-		}
-		return Config{}, err // Return any other read error
+	// Look up the active profile
+	name := file.ActiveProfileName()
+	profile, hasProfile := file.Profiles[name]
+	config := Config{
+		Personas: file.Personas,
+		Profile:  name,
 	}
 
-	var config Config
-	// Parse the JSON config file into the Config struct
-	if err = json.Unmarshal(data, &config); err != nil {
-		return Config{}, err // Return error if JSON unmarshalling fails
+	// Resolve the credentials from the layered sources
+	if err := resolveCredentials(&config, profile, hasProfile, true); err != nil {
+		return Config{}, err
+	}
+	if config.AccountID == "" || config.Token == "" {
+		switch {
+		case fileErr != nil:
+			return Config{}, fileErr // Return the missing file error if nothing else provided credentials
+		case !hasProfile:
+			return Config{}, fmt.Errorf("%w: %q", ErrProfileNotExist, name)
+		default:
+			return Config{}, fmt.Errorf("profile %q has no account ID or token", name)
+		}
 	}
 
 	return config, nil // Return the populated Config struct
 }
 
-// SaveConfig saves the given Config struct to the user's home directory.
+// SaveConfig saves the given Config struct as the active profile in the config file.
+// Only the credentials are saved; personas are managed through LoadFile and SaveFile.
 // It returns an error if saving fails.
 func SaveConfig(config Config) error {
 	// Check if the required config fields are provided
@@ -73,30 +100,81 @@ func SaveConfig(config Config) error {
 		return errors.New("config fields cannot be empty")
 	}
 
-	// Early check for initialization error
-	if err != nil {
-		return err // Return error if initialization failed
+	// Start from the existing file so other profiles are kept
+	file, err := LoadFile()
+	if err != nil && !errors.Is(err, ErrConfigNotExist) {
+		return err // Return error if the existing file can't be read
+	}
+
+	// Store the credentials under the profile they were loaded from, or the active one
+	name := config.Profile
+	if name == "" {
+		name = file.ActiveProfileName()
 	}
+	profile := file.Profiles[name]
+	profile.AccountID, profile.Token = config.AccountID, config.Token
+	file.SetProfile(name, profile)
+
+	return SaveFile(file)
+}
 
-	// Convert the Config struct to JSON
-	data, err := json.Marshal(config)
-	if err != nil {
-		return err // Return error if JSON marshalling fails
+// LoadOrSetup loads the configuration of the active profile. If no credentials are
+// configured yet, the user is prompted for them. They are checked with verify, which
+// returns a human readable report and an error if they don't work, and only saved if
+// they work or the user decides to save them anyway.
+func LoadOrSetup(verify func(Config) (report string, err error)) (Config, error) {
+	config, err := LoadConfig()
+	if err == nil {
+		return config, nil
 	}
 
-	// Write the JSON data to the config file
-	err = os.WriteFile(ConfigFile, data, 0644)
-	if err != nil {
-		return err // Return error if writing to the file fails
+	// Only prompt if there are no credentials yet, other failures such as a broken
+	// token_command must not be papered over by asking for a token
+	if !errors.Is(err, ErrConfigNotExist) && !errors.Is(err, ErrProfileNotExist) {
+		return Config{}, err
 	}
 
-	// On Windows, set the file attribute to hidden
-	if runtime.GOOS == "windows" {
-		err = WinFSATTR(ConfigFile)
-		if err != nil {
-			return fmt.Errorf("failed to set file attributes: %w", err) // Return error if setting attributes fails
-		}
+	// Prompt the user for the necessary information and verify it
+	config = PromptForConfig()
+	fmt.Println("\nVerifying credentials...")
+	report, err := verify(config)
+	fmt.Print(report)
+	if err != nil && !confirm("Save these credentials anyway? [y/N]: ") {
+		return Config{}, err
 	}
 
-	return nil // Return nil if saving was successful
+	if err := SaveConfig(config); err != nil {
+		return Config{}, err // Return error if saving the configuration fails
+	}
+	return config, nil
+}
+
+// PromptForConfig prompts the user for the necessary configuration information
+func PromptForConfig() Config {
+	profile := PromptForProfile(Profile{})
+	return Config{AccountID: profile.AccountID, Token: profile.Token}
+}
+
+// PromptForProfile prompts the user for the credentials profile lacks, keeping
+// the ones already set, e.g. by flags
+func PromptForProfile(profile Profile) Profile {
+	fmt.Printf("To use this service you need a Cloudflare API Token with WorkerAI Read and Edit Access from this Address %s\n", "https://dash.cloudflare.com/profile/api-tokens")
+	if profile.AccountID == "" {
+		fmt.Print("Enter your Cloudflare Account ID: ")
+		fmt.Scanln(&profile.AccountID)
+	}
+	if !profile.HasToken() {
+		fmt.Print("Enter your Cloudflare API Token: ")
+		fmt.Scanln(&profile.Token)
+	}
+	return profile
+}
+
+// confirm asks a yes/no question on stdin, defaulting to no
+func confirm(question string) bool {
+	var answer string
+	fmt.Print(question)
+	fmt.Scanln(&answer)
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
 }
@@ -8,8 +8,8 @@ import (
 )
 
 // WinFSATTR sets a file's attribute to hidden on Windows.
-// This is necessary because the configuration file is stored in the user's home
-// directory, which is typically not hidden.
+// This keeps the configuration file out of sight in Explorer, as older versions
+// stored it directly in the user's home directory.
 //
 // This function is a stub on non-Windows systems because there is no equivalent
 // concept of hidden files.
@@ -6,8 +6,8 @@ package auth
 // WinFSATTR is a no-op on non-Windows systems.
 //
 // On Windows, this function sets the file attribute of the specified file
-// to hidden. This is necessary because the configuration file is stored in
-// the user's home directory, which is typically not hidden.
+// to hidden. This keeps the configuration file out of sight in Explorer,
+// as older versions stored it directly in the user's home directory.
 //
 // This function is a stub on non-Windows systems because there is no
 // equivalent concept of hidden files. The function simply returns nil
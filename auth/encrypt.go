@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+)
+
+// EnvPassphrase is the environment variable providing the passphrase of an encrypted
+// token, so it can be decrypted without a prompt
+const EnvPassphrase = "MIDAI_PASSPHRASE"
+
+// scrypt cost parameters recommended for interactive logins
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32 // AES-256
+)
+
+// EncryptedToken is an API token encrypted with AES-256-GCM using a key derived
+// from a passphrase with scrypt. The parameters are stored so they can be raised later.
+type EncryptedToken struct {
+	KDF        string `json:"kdf"`        // Key derivation function, always "scrypt"
+	N          int    `json:"n"`          // scrypt CPU/memory cost
+	R          int    `json:"r"`          // scrypt block size
+	P          int    `json:"p"`          // scrypt parallelization
+	Salt       []byte `json:"salt"`       // Random salt of the key derivation, base64 in JSON
+	Nonce      []byte `json:"nonce"`      // Random GCM nonce, base64 in JSON
+	Ciphertext []byte `json:"ciphertext"` // Encrypted token including the GCM tag, base64 in JSON
+}
+
+// EncryptToken encrypts token with a key derived from passphrase
+func EncryptToken(token, passphrase string) (*EncryptedToken, error) {
+	if passphrase == "" {
+		return nil, errors.New("passphrase cannot be empty")
+	}
+
+	enc := &EncryptedToken{KDF: "scrypt", N: scryptN, R: scryptR, P: scryptP, Salt: make([]byte, 16)}
+	if _, err := rand.Read(enc.Salt); err != nil {
+		return nil, err
+	}
+
+	aead, err := enc.cipher(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	enc.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(enc.Nonce); err != nil {
+		return nil, err
+	}
+	enc.Ciphertext = aead.Seal(nil, enc.Nonce, []byte(token), nil)
+	return enc, nil
+}
+
+// Decrypt returns the token, failing if the passphrase is wrong
+func (e *EncryptedToken) Decrypt(passphrase string) (string, error) {
+	aead, err := e.cipher(passphrase)
+	if err != nil {
+		return "", err
+	}
+	// A nonce of the wrong size would make Open panic, the file was edited or damaged
+	if len(e.Nonce) != aead.NonceSize() {
+		return "", errors.New("failed to decrypt token: corrupted config file (invalid nonce)")
+	}
+	token, err := aead.Open(nil, e.Nonce, e.Ciphertext, nil)
+	if err != nil {
+		return "", errors.New("failed to decrypt token: wrong passphrase or corrupted config file")
+	}
+	return string(token), nil
+}
+
+// cipher derives the key from passphrase and returns the AES-GCM cipher using it
+func (e *EncryptedToken) cipher(passphrase string) (cipher.AEAD, error) {
+	if e.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported key derivation function %q", e.KDF)
+	}
+	key, err := scrypt.Key([]byte(passphrase), e.Salt, e.N, e.R, e.P, scryptKeyLen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ReadPassphrase returns the passphrase from MIDAI_PASSPHRASE, or asks for it on the
+// terminal without echoing it. With confirm set, the passphrase has to be typed twice.
+// Both are taken as is apart from line endings, so spaces are part of the passphrase.
+func ReadPassphrase(prompt string, confirm bool) (string, error) {
+	if passphrase := cleanPassphrase(os.Getenv(EnvPassphrase)); passphrase != "" {
+		return passphrase, nil
+	}
+
+	// Prompting needs a terminal, e.g. stdin may be a pipe in one-shot mode
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("the token is encrypted, set %s to provide the passphrase", EnvPassphrase)
+	}
+
+	read := func(prompt string) (string, error) {
+		fmt.Fprint(os.Stderr, prompt)
+		data, err := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		return cleanPassphrase(string(data)), err
+	}
+
+	passphrase, err := read(prompt)
+	if err != nil {
+		return "", err
+	}
+	if confirm {
+		again, err := read("Repeat the passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != passphrase {
+			return "", errors.New("passphrases don't match")
+		}
+	}
+	return passphrase, nil
+}
+
+// cleanPassphrase strips the line ending a passphrase may carry, e.g. when it was
+// read from a file into the environment
+func cleanPassphrase(passphrase string) string {
+	return strings.TrimRight(passphrase, "\r\n")
+}
+
+// ReadToken reads an API token without it showing up in the process list or the
+// shell history: from the terminal without echoing it, or as the first line of
+// stdin if that isn't a terminal, e.g. "pass show cloudflare | midai config set --encrypt".
+func ReadToken() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			return "", fmt.Errorf("failed to read the API Token from stdin: %w", err)
+		}
+		return strings.TrimSpace(line), nil
+	}
+
+	fmt.Fprint(os.Stderr, "Enter your Cloudflare API Token: ")
+	data, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	return strings.TrimSpace(string(data)), err
+}
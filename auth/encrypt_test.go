@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEncryptTokenRoundTrip(t *testing.T) {
+	enc, err := EncryptToken("secret-token", "correct horse")
+	if err != nil {
+		t.Fatalf("EncryptToken() error = %v", err)
+	}
+	if strings.Contains(string(enc.Ciphertext), "secret-token") {
+		t.Fatal("EncryptToken() ciphertext contains the plaintext token")
+	}
+
+	token, err := enc.Decrypt("correct horse")
+	if err != nil {
+		t.Fatalf("Decrypt() error = %v", err)
+	}
+	if token != "secret-token" {
+		t.Errorf("Decrypt() = %q, want %q", token, "secret-token")
+	}
+}
+
+func TestEncryptTokenEmptyPassphrase(t *testing.T) {
+	if _, err := EncryptToken("secret-token", ""); err == nil {
+		t.Error("EncryptToken() with an empty passphrase succeeded")
+	}
+}
+
+func TestDecryptFailures(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+		corrupt    func(e *EncryptedToken)
+		wantErr    string
+	}{
+		{name: "wrong passphrase", passphrase: "wrong", wantErr: "wrong passphrase"},
+		{name: "unknown KDF", passphrase: "correct horse", corrupt: func(e *EncryptedToken) { e.KDF = "argon2id" }, wantErr: "unsupported key derivation function"},
+		{name: "short nonce", passphrase: "correct horse", corrupt: func(e *EncryptedToken) { e.Nonce = e.Nonce[:4] }, wantErr: "invalid nonce"},
+		{name: "missing nonce", passphrase: "correct horse", corrupt: func(e *EncryptedToken) { e.Nonce = nil }, wantErr: "invalid nonce"},
+		{name: "tampered ciphertext", passphrase: "correct horse", corrupt: func(e *EncryptedToken) { e.Ciphertext[0] ^= 1 }, wantErr: "corrupted config file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc, err := EncryptToken("secret-token", "correct horse")
+			if err != nil {
+				t.Fatalf("EncryptToken() error = %v", err)
+			}
+			if tt.corrupt != nil {
+				tt.corrupt(enc)
+			}
+
+			token, err := enc.Decrypt(tt.passphrase)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Decrypt() = %q, %v, want an error containing %q", token, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+)
+
+// DefaultProfileName is the profile used when none is configured or selected
+const DefaultProfileName = "default"
+
+// ErrConfigNotExist is returned when the config file hasn't been created yet
+var ErrConfigNotExist = errors.New("config file does not exist")
+
+// ErrProfileNotExist is returned when the selected profile isn't defined in the config file
+var ErrProfileNotExist = errors.New("profile does not exist")
+
+// ActiveProfile selects the profile used by LoadConfig and SaveConfig, e.g. from a
+// --profile flag. When empty, the MIDAI_PROFILE environment variable is used, then
+// the default profile of the config file.
+var ActiveProfile string
+
+// Profile holds the credentials of a single Cloudflare account
+type Profile struct {
+	AccountID    string `json:"account_id"`
+	Token        string `json:"token,omitempty"`
+	TokenCommand string `json:"token_command,omitempty"` // Credential helper printing the token, takes precedence over Token
+
+	EncryptedToken *EncryptedToken `json:"encrypted_token,omitempty"` // Passphrase protected token, takes precedence over Token
+}
+
+// HasToken reports whether the profile has a token in any form
+func (p Profile) HasToken() bool {
+	return p.Token != "" || p.TokenCommand != "" || p.EncryptedToken != nil
+}
+
+// File is the content of the config file: named credential profiles, the
+// profile used by default and the personas shared by all profiles
+type File struct {
+	DefaultProfile string             `json:"default_profile"`
+	Profiles       map[string]Profile `json:"profiles"`
+	Personas       map[string]Persona `json:"personas,omitempty"`
+
+	// Fields of the legacy single-profile format, only read for migration
+	LegacyAccountID string `json:"account_id,omitempty"`
+	LegacyToken     string `json:"token,omitempty"`
+}
+
+// LoadFile reads the config file. A legacy single-profile file is migrated
+// into a "default" profile and written back in the current format.
+func LoadFile() (File, error) {
+	// Warn if other users can read the credentials
+	checkPermissions()
+
+	// Read the config file
+	data, err := os.ReadFile(ConfigFile)
+	if err != nil {
+		// Return a specific error if the config file does not exist
+		if errors.Is(err, os.ErrNotExist) {
+			return File{}, ErrConfigNotExist
+		}
+		return File{}, err // Return any other read error
+	}
+
+	var file File
+	// Parse the JSON config file into the File struct
+	if err = json.Unmarshal(data, &file); err != nil {
+		return File{}, err // Return error if JSON unmarshalling fails
+	}
+
+	// Migrate the legacy format, which only held a single account ID and token
+	if file.LegacyAccountID != "" || file.LegacyToken != "" {
+		file.SetProfile(DefaultProfileName, Profile{AccountID: file.LegacyAccountID, Token: file.LegacyToken})
+		file.LegacyAccountID, file.LegacyToken = "", ""
+		if err := SaveFile(file); err != nil {
+			return File{}, fmt.Errorf("failed to migrate config file: %w", err)
+		}
+	}
+
+	return file, nil // Return the populated File struct
+}
+
+// SaveFile writes the config file to the MidAI config directory.
+// It returns an error if saving fails.
+func SaveFile(file File) error {
+	// Create the config directory if it doesn't exist yet
+	if err := os.MkdirAll(filepath.Dir(ConfigFile), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	// Convert the File struct to JSON
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err // Return error if JSON marshalling fails
+	}
+
+	// Write the JSON data to the config file, readable only by the user
+	err = os.WriteFile(ConfigFile, data, 0600)
+	if err != nil {
+		return err // Return error if writing to the file fails
+	}
+
+	// WriteFile keeps the mode of an existing file, so tighten it explicitly on Unix
+	if runtime.GOOS != "windows" {
+		if err := os.Chmod(ConfigFile, 0600); err != nil {
+			return fmt.Errorf("failed to set file permissions: %w", err)
+		}
+	}
+
+	// On Windows, set the file attribute to hidden
+	if runtime.GOOS == "windows" {
+		err = WinFSATTR(ConfigFile)
+		if err != nil {
+			return fmt.Errorf("failed to set file attributes: %w", err) // Return error if setting attributes fails
+		}
+	}
+
+	return nil // Return nil if saving was successful
+}
+
+// checkPermissions prints a warning if the config file is accessible by other users.
+// Windows doesn't use Unix permission bits, so nothing is checked there.
+func checkPermissions() {
+	if runtime.GOOS == "windows" {
+		return
+	}
+	info, err := os.Stat(ConfigFile)
+	if err != nil {
+		return
+	}
+	if mode := info.Mode().Perm(); mode&0077 != 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %s is accessible by other users (mode %04o), restrict it with: chmod 600 %s\n", ConfigFile, mode, ConfigFile)
+	}
+}
+
+// ActiveProfileName returns the name of the profile LoadConfig uses: the one selected
+// by ActiveProfile, MIDAI_PROFILE or the file's default, in that order
+func (f File) ActiveProfileName() string {
+	if ActiveProfile != "" {
+		return ActiveProfile
+	}
+	if env := os.Getenv("MIDAI_PROFILE"); env != "" {
+		return env
+	}
+	if f.DefaultProfile != "" {
+		return f.DefaultProfile
+	}
+	return DefaultProfileName
+}
+
+// ProfileNames returns the names of all profiles, sorted
+func (f File) ProfileNames() []string {
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetProfile adds or replaces a profile. The first profile becomes the default.
+func (f *File) SetProfile(name string, profile Profile) {
+	if f.Profiles == nil {
+		f.Profiles = map[string]Profile{}
+	}
+	f.Profiles[name] = profile
+	if f.DefaultProfile == "" {
+		f.DefaultProfile = name
+	}
+}
+
+// RenameProfile renames a profile, keeping it the default if it was
+func (f *File) RenameProfile(oldName, newName string) error {
+	profile, ok := f.Profiles[oldName]
+	if !ok {
+		return fmt.Errorf("profile %q does not exist", oldName)
+	}
+	if _, exists := f.Profiles[newName]; exists {
+		return fmt.Errorf("profile %q already exists", newName)
+	}
+	delete(f.Profiles, oldName)
+	f.Profiles[newName] = profile
+	if f.DefaultProfile == oldName {
+		f.DefaultProfile = newName
+	}
+	return nil
+}
+
+// RemoveProfile removes a profile. The default profile can only be removed if it's the last one.
+func (f *File) RemoveProfile(name string) error {
+	if _, ok := f.Profiles[name]; !ok {
+		return fmt.Errorf("profile %q does not exist", name)
+	}
+	if name == f.DefaultProfile && len(f.Profiles) > 1 {
+		return fmt.Errorf("profile %q is the default, switch to another profile first", name)
+	}
+	delete(f.Profiles, name)
+	if name == f.DefaultProfile {
+		f.DefaultProfile = ""
+	}
+	return nil
+}
+
+// UseProfile makes an existing profile the default
+func (f *File) UseProfile(name string) error {
+	if _, ok := f.Profiles[name]; !ok {
+		return fmt.Errorf("profile %q does not exist", name)
+	}
+	f.DefaultProfile = name
+	return nil
+}
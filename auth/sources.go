@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Environment variables that take precedence over the config file, e.g. in CI
+const (
+	EnvAccountID = "CLOUDFLARE_ACCOUNT_ID" // Overrides the account ID of the active profile
+	EnvAPIToken  = "CLOUDFLARE_API_TOKEN"  // Overrides the API token of the active profile
+)
+
+// resolveCredentials fills the account ID and token of config from the layered
+// credential sources, in order of precedence: the environment, the profile's
+// token_command or encrypted token (for the token only) and finally the profile itself.
+// The chosen sources are recorded in AccountIDSource and TokenSource.
+// With useEnv unset, the environment is skipped.
+func resolveCredentials(config *Config, profile Profile, hasProfile, useEnv bool) error {
+	profileSource := fmt.Sprintf("profile %q in %s", config.Profile, ConfigFile)
+
+	// Resolve the account ID
+	if accountID := os.Getenv(EnvAccountID); useEnv && accountID != "" {
+		config.AccountID = accountID
+		config.AccountIDSource = "environment variable " + EnvAccountID
+	} else if hasProfile && profile.AccountID != "" {
+		config.AccountID = profile.AccountID
+		config.AccountIDSource = profileSource
+	}
+
+	// Resolve the token
+	switch {
+	case useEnv && os.Getenv(EnvAPIToken) != "":
+		config.Token = os.Getenv(EnvAPIToken)
+		config.TokenSource = "environment variable " + EnvAPIToken
+	case hasProfile && profile.TokenCommand != "":
+		token, err := runTokenCommand(profile.TokenCommand)
+		if err != nil {
+			return err
+		}
+		config.Token = token
+		config.TokenSource = fmt.Sprintf("token_command of profile %q (%s)", config.Profile, profile.TokenCommand)
+	case hasProfile && profile.EncryptedToken != nil:
+		passphrase, err := ReadPassphrase(fmt.Sprintf("Passphrase for profile %q: ", config.Profile), false)
+		if err != nil {
+			return err
+		}
+		token, err := profile.EncryptedToken.Decrypt(passphrase)
+		if err != nil {
+			return err
+		}
+		config.Token = token
+		config.TokenSource = fmt.Sprintf("encrypted token of profile %q in %s", config.Profile, ConfigFile)
+	case hasProfile && profile.Token != "":
+		config.Token = profile.Token
+		config.TokenSource = profileSource
+	}
+	return nil
+}
+
+// ProfileConfig resolves the credentials stored in a profile, ignoring the environment,
+// e.g. to verify a profile before it is saved
+func ProfileConfig(name string, profile Profile) (Config, error) {
+	config := Config{Profile: name}
+	err := resolveCredentials(&config, profile, true, false)
+	return config, err
+}
+
+// runTokenCommand runs a credential helper such as "pass show cloudflare/workers-ai"
+// through the system shell and returns the first line it prints to stdout
+func runTokenCommand(command string) (string, error) {
+	// Run the command through the shell so pipes and quoting work as expected
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+
+	// Let the helper interact with the user, e.g. to unlock a password store
+	var stdout, stderr bytes.Buffer
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("token_command %q failed: %w: %s", command, err, strings.TrimSpace(stderr.String()))
+	}
+
+	// Only the first line is the token, e.g. "pass" prints further metadata after it
+	token, _, _ := strings.Cut(strings.TrimSpace(stdout.String()), "\n")
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", fmt.Errorf("token_command %q printed no token", command)
+	}
+	return token, nil
+}
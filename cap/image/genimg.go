@@ -1,19 +1,22 @@
 package gentext
 
 import (
-	"MidAI/auth"
+	"MidAI/client"
 	model "MidAI/models"
+	"MidAI/settings"
 	"bufio"
-	"bytes"
+	"context"
 	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"math/rand"
-	"net/http"
 	"os"
+	"os/signal"
 	"path"
+	"path/filepath"
+	"strings"
+	"time"
 )
 
 // Message represents a single message in the conversation history
@@ -26,62 +29,55 @@ type RequestBody struct {
 	Prompt string `json:"prompt"` // Conversation history
 }
 
-// ApiResponse is the response from the AI API
+// ApiResponse is the result returned by the AI API
 type ApiResponse struct {
-	Result struct {
-		Response string `json:"image"` // Response from the assistant
-	} `json:"result"`
+	Image string `json:"image"` // Base64-encoded generated image
 }
 
+// Options configures an image generation session started with Generate
+type Options struct {
+	Model   string // Name of the model to use; the image_model setting, or a model the user picks from a table, when empty
+	Prompt  string // Image description; when set a single image is generated instead of starting the prompt loop
+	Output  string // Path the generated image is saved to; a new file in the output_dir setting for every image when empty
+	Refresh bool   // Fetch the model catalog even if the cached one is still fresh
+}
+
+// Prompt starts an interactive image generation session, asking the user for every setting
 func Prompt() {
-	// Load the configuration from the user's home directory
-	config, err := auth.LoadConfig()
-	if err != nil {
-		// If the configuration doesn't exist, prompt the user for the necessary information
-		config = promptForConfig()
-		if err := auth.SaveConfig(config); err != nil {
-			// If there's an error saving the configuration, panic
-			panic(err)
-		}
+	if err := Generate(context.Background(), Options{}); err != nil {
+		fmt.Println("Error:", err)
 	}
+}
 
-	// Fetch the list of available models
-	models, err := model.GetAvailableModels(config)
+// Generate starts an image generation session configured by opts. Cancelling ctx
+// aborts the session; Ctrl-C while an image is generated only cancels that image.
+func Generate(ctx context.Context, opts Options) error {
+	// Create the API client shared by all requests of this session,
+	// setting up the configuration first if necessary
+	c, err := client.LoadOrSetup(ctx)
 	if err != nil {
-		// If there's an error fetching the models, print the error and exit
-		fmt.Println("Error fetching models:", err)
-		return
+		return err
 	}
 
-	// Filter only the models with "Text-to-Image" capability
-	var textToImageModels []model.Model
-	for _, m := range models {
-		if m.Task.Capability == "Text-to-Image" {
-			textToImageModels = append(textToImageModels, m)
+	// Refresh the cached model catalog first if requested
+	if opts.Refresh {
+		if _, err := model.GetAvailableModels(ctx, c, model.ListOptions{Refresh: true}); err != nil {
+			return err
 		}
 	}
-	// Print the table of only Text-to-Image models
-	model.PrintModelsTable(textToImageModels)
 
-	selectedModel, err := model.SelectModel(models)
+	// Use the requested or default model, or let the user pick one
+	if opts.Model == "" {
+		opts.Model = settings.String(settings.ImageModel)
+	}
+	selectedModel, err := chooseModel(ctx, c, opts.Model)
 	if err != nil {
-		// Select a random model from the list with the "Text-to-Image" capability
-		var ids []int
-		for i, model := range models {
-			if model.Task.Capability == "Text-to-Image" {
-				ids = append(ids, i)
-			}
-		}
-		if len(ids) == 0 {
-			fmt.Println("No models with the 'Text-to-Image' capability available")
-			return
-		}
-		randomIndex := rand.Intn(len(ids))       // Generate a random index within the range of IDs
-		selectedModel = models[ids[randomIndex]] // Select the model at that index
-		// If there's an error selecting a model, print the error and exit
-		fmt.Printf("\nWe select the \"%s\" for you.\n", path.Base(selectedModel.Name))
-		//fmt.Println("Error selecting model:", err)
-		//return
+		return err
+	}
+
+	// A single prompt is answered without starting the prompt loop
+	if opts.Prompt != "" {
+		return generate(ctx, c, selectedModel.Name, opts.Prompt, opts.Output)
 	}
 
 	// Create a reader to read the user's input
@@ -91,51 +87,107 @@ func Prompt() {
 		// Ask the user for their message
 		fmt.Print("\nEnter your message for the assistant (or press 'Enter' or type 'q' to exit): ")
 		userInput, err := reader.ReadString('\n')
-		if err != nil {
-			// If there's an error reading the user's input, panic
-			panic(err)
+		if err != nil && (err != io.EOF || userInput == "") {
+			// Stop when the input ends or can't be read
+			if err != io.EOF {
+				return err
+			}
+			userInput = ""
 		}
-		userInput = userInput[:len(userInput)-1]
+		userInput = strings.TrimRight(userInput, "\r\n")
 
 		if userInput == "" || userInput == "q" {
 			// If the user presses 'Enter' or types 'q', exit the loop
 			fmt.Printf("\nHave a nice time:) Goodbye!\n")
-			break
+			return nil
 		}
 
-		// Build the API URL
-		apiURL := fmt.Sprintf("https://api.cloudflare.com/client/v4/accounts/%s/ai/run/%s", config.AccountID, selectedModel.Name)
+		// Generate the image, reporting failures and letting the user retry.
+		// Ctrl-C cancels only this image and returns to the prompt.
+		genCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+		err = generate(genCtx, c, selectedModel.Name, userInput, opts.Output)
+		stop()
+		if errors.Is(err, context.Canceled) && ctx.Err() == nil {
+			fmt.Println("\nImage generation cancelled.")
+		} else if err != nil {
+			fmt.Println("Error generating image:", err)
+		}
+	}
+}
 
-		// Build the request body
-		requestBody := RequestBody{
-			Prompt: userInput,
+// chooseModel returns the "Text-to-Image" model matching name, letting the user pick one
+// from a table when name is empty or matches several models, see model.PickModel
+func chooseModel(ctx context.Context, c *client.Client, name string) (model.Model, error) {
+	// Fetch the list of available models
+	models, err := model.GetAvailableModels(ctx, c, model.ListOptions{})
+	if err != nil {
+		if strings.HasPrefix(name, "@") {
+			return model.Model{Name: name}, nil // A full model ID can be used without the catalog
 		}
+		return model.Model{}, err
+	}
 
-		// Get the assistant's response
-		imageData, err := getAssistantResponse(apiURL, config.Token, requestBody)
-		if err != nil {
-			// If there's an error getting the assistant's response, panic
-			panic(err)
+	selectedModel, err := model.PickModel(models, "Text-to-Image", name)
+	if err != nil && name == "" {
+		// Select a random model from the list with the "Text-to-Image" capability
+		textToImageModels := model.WithTask(models, "Text-to-Image")
+		if len(textToImageModels) == 0 {
+			return model.Model{}, fmt.Errorf("no models with the 'Text-to-Image' capability available")
 		}
+		randomIndex := rand.Intn(len(textToImageModels)) // Generate a random index within the range of models
+		selectedModel = textToImageModels[randomIndex]   // Select the model at that index
+		fmt.Printf("\nWe select the \"%s\" for you.\n", path.Base(selectedModel.Name))
+		return selectedModel, nil
+	}
+	return selectedModel, err
+}
+
+// generate asks the model for an image matching prompt and saves it to output,
+// or to a new file in the output directory when output is empty
+func generate(ctx context.Context, c *client.Client, modelName, prompt, output string) error {
+	// Build the request body
+	requestBody := RequestBody{
+		Prompt: prompt,
+	}
 
-		// Save image
-		if err := saveBase64Image(imageData, "generated_image.png"); err != nil {
-			fmt.Println("Error saving image:", err)
-			continue
+	// Get the assistant's response
+	imageData, err := getAssistantResponse(ctx, c, modelName, requestBody)
+	if err != nil {
+		return err
+	}
+
+	// Pick a file in the image directory unless the user chose one
+	if output == "" {
+		if output, err = newOutputPath(settings.String(settings.OutputDir), time.Now()); err != nil {
+			return err
 		}
+	}
 
-		fmt.Println("✅ Image saved as 'generated_image.png'")
+	// Save image
+	if err := saveBase64Image(imageData, output); err != nil {
+		return fmt.Errorf("failed to save image: %w", err)
 	}
+
+	fmt.Printf("✅ Image saved as '%s'\n", output)
+	return nil
 }
 
-// promptForConfig prompts the user for the necessary configuration information
-func promptForConfig() auth.Config {
-	var config auth.Config
-	fmt.Print("Enter your Cloudflare Account ID: ")
-	fmt.Scanln(&config.AccountID)
-	fmt.Print("Enter your Cloudflare API Token: ")
-	fmt.Scanln(&config.Token)
-	return config
+// newOutputPath creates the output directory dir and returns an unused file name in it,
+// such as "image-20250102-150405.png"
+func newOutputPath(dir string, now time.Time) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create image directory: %w", err)
+	}
+
+	// Number the file if several images are generated within a second
+	name := "image-" + now.Format("20060102-150405")
+	output := filepath.Join(dir, name+".png")
+	for i := 2; ; i++ {
+		if _, err := os.Stat(output); errors.Is(err, os.ErrNotExist) {
+			return output, nil
+		}
+		output = filepath.Join(dir, fmt.Sprintf("%s-%d.png", name, i))
+	}
 }
 
 // Save base64-encoded image to a file
@@ -156,55 +208,13 @@ func decodeBase64(base64String string) ([]byte, error) {
 	return decoded, nil
 }
 
-// getAssistantResponse makes an API call to the AI API to get the assistant's response
-func getAssistantResponse(url, token string, requestBody RequestBody) (string, error) {
-	// Marshal the request body to JSON
-	body, err := json.Marshal(requestBody)
-	if err != nil {
-		return "", err
-	}
-
-	fmt.Println("\n🟢 Sending Request:")
-	fmt.Println("🔹 URL:", url)
-	fmt.Println("🔹 Headers: Authorization=Bearer <hidden>, Content-Type=application/json")
-	fmt.Println("🔹 Payload:", string(body)) // Print request body as string
-
-	// Create a new request
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
-	if err != nil {
-		return "", err
-	}
-
-	// Set the authorization header
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
-
-	// Make the request
-	client := &http.Client{}
-	res, err := client.Do(req)
-	if err != nil {
+// getAssistantResponse makes an API call to the AI API to get the generated image
+func getAssistantResponse(ctx context.Context, c *client.Client, modelName string, requestBody RequestBody) (string, error) {
+	var result ApiResponse
+	if err := c.Run(ctx, modelName, requestBody, &result); err != nil {
 		return "", err
 	}
-	defer res.Body.Close()
-
-	// Read the response body
-	respBody, err := io.ReadAll(res.Body)
-	if err != nil {
-		return "", err
-	}
-
-	// Log the raw response
-	fmt.Println("\n🔵 Received Response:")
-	fmt.Println("🔹 Status Code:", res.Status)
-	fmt.Println("🔹 Headers:", res.Header)
-	fmt.Println("🔹 Body:", string(respBody)) // Print full response
-
-	// Unmarshal the response body to the ApiResponse struct
-	var apiResponse ApiResponse
-	if err := json.Unmarshal(respBody, &apiResponse); err != nil {
-		return "", fmt.Errorf("failed to parse JSON response: %w\nRaw Response: %s", err, string(respBody))
-	}
 
-	// Return the assistant's response
-	return apiResponse.Result.Response, nil
+	// Return the base64-encoded image
+	return result.Image, nil
 }
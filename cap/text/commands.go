@@ -0,0 +1,225 @@
+package gentext
+
+import (
+	"MidAI/session"
+	"MidAI/table"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// slashCommand is a command that can be typed into the chat loop, such as "/model"
+type slashCommand struct {
+	name string                                    // Name including the leading slash
+	args string                                    // Arguments shown in the help
+	help string                                    // One line description shown in the help
+	run  func(cv *conversation, args string) error // Handler receiving the trimmed arguments
+}
+
+// slashCommands lists the commands of the chat loop in the order /help shows them.
+// It's populated in init because /help refers to it.
+var slashCommands []slashCommand
+
+func init() {
+	slashCommands = []slashCommand{
+		{"/help", "", "Show this help", cmdHelp},
+		{"/model", "[name]", "Switch to another model by full or partial name, picking from a table without a name", cmdModel},
+		{"/system", "[prompt]", "Show or change the system prompt", cmdSystem},
+		{"/persona", "[name]", "Switch to a persona from the config file, listing them without a name", cmdPersona},
+		{"/params", "[name=value ...|reset]", "Show or adjust the generation parameters", cmdParams},
+		{"/history", "", "Show the conversation history", cmdHistory},
+		{"/clear", "", "Start over with an empty history in a new session", cmdClear},
+		{"/retry", "", "Regenerate the last answer", cmdRetry},
+		{"/save", "", "Save the session and show its ID", cmdSave},
+		{"/load", "[id]", "Continue a saved session, listing recent ones without an ID", cmdLoad},
+		{"/quit", "", "Leave the chat", nil},
+	}
+}
+
+// runCommand runs the slash command typed by the user
+func (cv *conversation) runCommand(input string) error {
+	name, args, _ := strings.Cut(input, " ")
+	for _, cmd := range slashCommands {
+		if cmd.name == name && cmd.run != nil {
+			return cmd.run(cv, strings.TrimSpace(args))
+		}
+	}
+	return fmt.Errorf("unknown command %q, type /help to list the commands", name)
+}
+
+// cmdHelp implements /help
+func cmdHelp(_ *conversation, _ string) error {
+	fmt.Println("\nCommands:")
+	t := table.New(table.Column{}, table.Column{Wrap: true})
+	t.Indent = 2
+	for _, cmd := range slashCommands {
+		t.Append(strings.TrimSpace(cmd.name+" "+cmd.args), cmd.help)
+	}
+	return t.Print()
+}
+
+// cmdModel implements /model
+func cmdModel(cv *conversation, name string) error {
+	selectedModel, err := chooseModel(cv.ctx, cv.client, name)
+	if err != nil {
+		return err
+	}
+	cv.model = selectedModel
+	cv.session.Model = selectedModel.Name
+	fmt.Printf("Switched to \"%s\".\n", path.Base(selectedModel.Name))
+	return nil
+}
+
+// cmdSystem implements /system
+func cmdSystem(cv *conversation, prompt string) error {
+	if prompt != "" {
+		cv.session.SystemPrompt = prompt
+	}
+	fmt.Println("System prompt:", cv.session.SystemPrompt)
+	return nil
+}
+
+// cmdPersona implements /persona
+func cmdPersona(cv *conversation, name string) error {
+	if name == "" {
+		fmt.Println("Available personas:", personaNames(cv.config))
+		return nil
+	}
+
+	persona, params, err := lookupPersona(cv.config, name)
+	if err != nil {
+		return err
+	}
+	if persona.SystemPrompt != "" {
+		cv.session.SystemPrompt = persona.SystemPrompt
+	}
+	if persona.Model != "" {
+		cv.model = lookupModel(cv.ctx, cv.client, persona.Model)
+		cv.session.Model = persona.Model
+	}
+	cv.session.Persona = name
+	cv.params = params
+	fmt.Printf("Switched to persona %q using \"%s\".\n", name, path.Base(cv.model.Name))
+	return nil
+}
+
+// cmdParams implements /params, e.g. "/params temperature=0.2 max_tokens=1024"
+func cmdParams(cv *conversation, settings string) error {
+	err := updateParams(&cv.params, settings)
+	fmt.Println("Generation parameters:", cv.params)
+	return err
+}
+
+// updateParams applies space separated "name=value" settings to params.
+// "reset" restores the model's defaults; an invalid setting leaves params unchanged.
+func updateParams(params *Params, settings string) error {
+	updated := *params
+	for _, setting := range strings.Fields(settings) {
+		if setting == "reset" {
+			updated = Params{}
+			continue
+		}
+		name, value, ok := strings.Cut(setting, "=")
+		if !ok {
+			return fmt.Errorf("expected name=value, got %q", setting)
+		}
+		if err := updated.Set(name, value); err != nil {
+			return err
+		}
+	}
+	*params = updated
+	return nil
+}
+
+// cmdHistory implements /history
+func cmdHistory(cv *conversation, _ string) error {
+	if len(cv.session.Messages) == 0 {
+		fmt.Println("The history is empty.")
+		return nil
+	}
+
+	fmt.Printf("\n[system] %s\n", cv.session.SystemPrompt)
+	for _, m := range cv.session.Messages {
+		fmt.Printf("\n[%s] %s\n", m.Role, m.Content)
+	}
+	return nil
+}
+
+// cmdClear implements /clear. The current session stays saved and a new one is started.
+func cmdClear(cv *conversation, _ string) error {
+	next := session.New(cv.model.Name, cv.session.SystemPrompt)
+	next.Persona = cv.session.Persona
+	cv.session = next
+	fmt.Println("History cleared.")
+	return nil
+}
+
+// cmdRetry implements /retry
+func cmdRetry(cv *conversation, _ string) error {
+	messages := cv.session.Messages
+	if len(messages) < 2 || messages[len(messages)-1].Role != "assistant" {
+		return fmt.Errorf("there is no answer to regenerate")
+	}
+
+	// Drop the last exchange and send its message again, restoring it if that fails
+	userInput := messages[len(messages)-2].Content
+	cv.session.Messages = messages[:len(messages)-2]
+	if err := cv.send(userInput); err != nil {
+		cv.session.Messages = messages
+		return err
+	}
+	return nil
+}
+
+// cmdSave implements /save
+func cmdSave(cv *conversation, _ string) error {
+	if err := session.Save(cv.session); err != nil {
+		return err
+	}
+	fmt.Printf("Session saved as %s.\n", cv.session.ID)
+	return nil
+}
+
+// cmdLoad implements /load
+func cmdLoad(cv *conversation, id string) error {
+	if id == "" {
+		sessions, err := session.List()
+		if err != nil {
+			return err
+		}
+		if len(sessions) == 0 {
+			fmt.Println("No saved sessions.")
+			return nil
+		}
+		fmt.Println("Recent sessions:")
+		t := table.New(
+			table.Column{Header: "ID"},
+			table.Column{Header: "MODEL"},
+			table.Column{Header: "UPDATED"},
+			table.Column{Header: "TITLE", Max: 50},
+		)
+		t.Indent = 2
+		for _, s := range sessions[:min(len(sessions), 10)] {
+			t.Append(s.ID, path.Base(s.Model), s.UpdatedAt.Format("2006-01-02 15:04"), s.Title())
+		}
+		return t.Print()
+	}
+
+	loaded, err := session.Load(id)
+	if err != nil {
+		return err
+	}
+
+	// Restore the model and the parameters of the persona the session was held with
+	params := Params{}
+	if loaded.Persona != "" {
+		if _, personaParams, err := lookupPersona(cv.config, loaded.Persona); err == nil {
+			params = personaParams
+		}
+	}
+	cv.session = loaded
+	cv.model = lookupModel(cv.ctx, cv.client, loaded.Model)
+	cv.params = params
+	fmt.Printf("Loaded session %s with %d messages using \"%s\".\n", loaded.ID, len(loaded.Messages), path.Base(loaded.Model))
+	return nil
+}
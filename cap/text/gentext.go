@@ -2,207 +2,395 @@ package gentext
 
 import (
 	"MidAI/auth"
+	"MidAI/client"
 	model "MidAI/models"
+	"MidAI/session"
+	"MidAI/settings"
 	"bufio"
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
-	"net/http"
 	"os"
+	"os/signal"
 	"path"
+	"strings"
 )
 
 // Message represents a single message in the conversation history
-type Message struct {
-	Role    string `json:"role"`    // Role of the message (user or assistant)
-	Content string `json:"content"` // Content of the message
-}
+type Message = session.Message
 
 // RequestBody is the request body for the AI API
 type RequestBody struct {
-	Messages []Message `json:"messages"` // Conversation history
+	Messages []Message `json:"messages"`         // Conversation history
+	Stream   bool      `json:"stream,omitempty"` // Ask the API to stream tokens as server-sent events
+	Params             // Optional generation parameters
 }
 
-// ApiResponse is the response from the AI API
+// ApiResponse is the result returned by the AI API. While streaming, every
+// server-sent event frame carries one of these with the newly generated tokens.
 type ApiResponse struct {
-	Result struct {
-		Response string `json:"response"` // Response from the assistant
-	} `json:"result"`
+	Response string `json:"response"` // Response from the assistant
 }
 
-var maxHistory int // Maximum number of messages to keep in the conversation history
+// Options configures a chat session started with Chat
+type Options struct {
+	Model         string // Name of the model to use; the text_model setting, or a model the user picks from a table, when empty
+	Prompt        string // Message to send; when set a single answer is printed instead of starting the conversation loop
+	HistoryTokens int    // Token budget of the history sent to the model; the history_tokens setting when zero
+	Resume        string // ID of a saved session to continue; a new session is started when empty
+	SystemPrompt  string // System message; taken from the persona, the resumed session or the system_prompt setting when empty
+	Persona       string // Name of a persona from the config file providing the system prompt, model and parameters
+	Params        Params // Generation parameters, overriding those of the persona
+	Refresh       bool   // Fetch the model catalog even if the cached one is still fresh
+}
 
+// Prompt starts an interactive chat session, asking the user for every setting
 func Prompt() {
-	// Load the configuration from the user's home directory
-	config, err := auth.LoadConfig()
-	if err != nil {
-		// If the configuration doesn't exist, prompt the user for the necessary information
-		config = promptForConfig()
-		if err := auth.SaveConfig(config); err != nil {
-			// If there's an error saving the configuration, panic
-			panic(err)
-		}
+	if err := Chat(context.Background(), Options{}); err != nil {
+		fmt.Println("Error:", err)
 	}
+}
 
-	// Fetch the list of available models
-	models, err := model.GetAvailableModels(config)
+// Chat starts a chat session configured by opts. Cancelling ctx aborts the session;
+// Ctrl-C while an answer is generated only cancels that answer.
+func Chat(ctx context.Context, opts Options) error {
+	// Create the API client shared by all requests of this session,
+	// setting up the configuration first if necessary
+	c, err := client.LoadOrSetup(ctx)
 	if err != nil {
-		// If there's an error fetching the models, print the error and exit
-		fmt.Println("Error fetching models:", err)
-		return
+		return err
 	}
+	config := c.Config
 
-	// Filter only the models with "Text Generation" capability
-	var textToImageModels []model.Model
-	for _, m := range models {
-		if m.Task.Capability == "Text Generation" {
-			textToImageModels = append(textToImageModels, m)
+	// Refresh the cached model catalog first if requested
+	if opts.Refresh {
+		if _, err := model.GetAvailableModels(ctx, c, model.ListOptions{Refresh: true}); err != nil {
+			return err
 		}
 	}
-	// Print the table of only Text Generation models
-	model.PrintModelsTable(textToImageModels)
 
-	selectedModel, err := model.SelectModel(models)
-	if err != nil {
-		// Select a random model from the list with the "Text Generation" capability
-		var ids []int
-		for i, model := range models {
-			if model.Task.Capability == "Text Generation" {
-				ids = append(ids, i)
-			}
+	// Load the session to resume, continuing with its persona unless another one is requested
+	var sess *session.Session
+	if opts.Resume != "" {
+		if sess, err = session.Load(opts.Resume); err != nil {
+			return err
 		}
-		if len(ids) == 0 {
-			fmt.Println("No models with the 'Text Generation' capability available")
-			return
+		if opts.Persona == "" {
+			opts.Persona = sess.Persona
 		}
-		randomIndex := rand.Intn(len(ids))       // Generate a random index within the range of IDs
-		selectedModel = models[ids[randomIndex]] // Select the model at that index
-		// If there's an error selecting a model, print the error and exit
-		fmt.Printf("\nWe select the \"%s\" for you.\n", path.Base(selectedModel.Name))
-		//fmt.Println("Error selecting model:", err)
-		//return
 	}
 
-	fmt.Println("\n1. History size: 1  2. History size: 2  3. History size: 3  4. History size: 4  5. History size: 5  6. History size: 6 (default)  7. History size: 7  8. History size: 8  9. History size: 9  10. History size: 10")
+	// Explicit settings win over the persona, which wins over the resumed session
+	if err := applyPersona(config, &opts); err != nil {
+		return err
+	}
+	if sess != nil {
+		if opts.Model == "" {
+			opts.Model = sess.Model
+		}
+		if opts.SystemPrompt == "" {
+			opts.SystemPrompt = sess.SystemPrompt
+		}
+	}
+	applySettings(&opts)
+	if err := opts.Params.Validate(); err != nil {
+		return err
+	}
+	if opts.HistoryTokens < 0 {
+		return fmt.Errorf("history token budget must not be negative, got %d", opts.HistoryTokens)
+	}
+
+	// Use the requested model, or let the user pick one
+	selectedModel, err := chooseModel(ctx, c, opts.Model)
+	if err != nil {
+		return err
+	}
+
+	// Start a new session unless one is resumed
+	if sess == nil {
+		sess = session.New(selectedModel.Name, opts.SystemPrompt)
+	}
+	sess.Model = selectedModel.Name
+	sess.SystemPrompt = opts.SystemPrompt
+	sess.Persona = opts.Persona
+	if len(sess.Messages) > 0 {
+		fmt.Printf("\nResumed session %s with %d messages.\n", sess.ID, len(sess.Messages))
+	}
 
-	_, err = fmt.Scanln(&maxHistory)
-	if err != nil || maxHistory < 1 || maxHistory > 10 {
-		fmt.Printf("\nInvalid selection. We select the 6 size for you.\n")
-		maxHistory = 6
-		fmt.Printf("History size set to: %d\n", maxHistory)
-		// If there's an error reading the user's input, panic
-		//panic(err)
+	cv := &conversation{
+		ctx:           ctx,
+		client:        c,
+		config:        config,
+		model:         selectedModel,
+		params:        opts.Params,
+		session:       sess,
+		historyTokens: opts.HistoryTokens,
 	}
 
-	// Initialize the conversation history
-	conversationHistory := make([]Message, 0, maxHistory)
+	// A single prompt is answered without starting the conversation loop
+	if opts.Prompt != "" {
+		return cv.send(opts.Prompt)
+	}
 
 	// Create a reader to read the user's input
 	reader := bufio.NewReader(os.Stdin)
 
 	for {
 		// Ask the user for their message
-		fmt.Print("\nEnter your message for the assistant (or press 'Enter' or type 'q' to exit): ")
+		fmt.Print("\nEnter your message for the assistant (type /help for commands, press 'Enter' or type 'q' to exit): ")
 		userInput, err := reader.ReadString('\n')
-		if err != nil {
-			// If there's an error reading the user's input, panic
-			panic(err)
+		if err != nil && (err != io.EOF || userInput == "") {
+			// Stop when the input ends or can't be read
+			if err != io.EOF {
+				return err
+			}
+			userInput = ""
 		}
-		userInput = userInput[:len(userInput)-1]
+		userInput = strings.TrimRight(userInput, "\r\n")
 
-		if userInput == "" || userInput == "q" {
+		if userInput == "" || userInput == "q" || userInput == "/quit" {
 			// If the user presses 'Enter' or types 'q', exit the loop
+			if len(cv.session.Messages) > 0 {
+				fmt.Printf("\nSession saved as %s, resume it with: midai chat --resume %s\n", cv.session.ID, cv.session.ID)
+			}
 			fmt.Printf("\nHave a nice time:) Goodbye!\n")
-			break
+			return nil
 		}
 
-		// Add the user's message to the conversation history
-		conversationHistory = appendMessage(conversationHistory, "user", userInput)
-
-		// Build the API URL
-		apiURL := fmt.Sprintf("https://api.cloudflare.com/client/v4/accounts/%s/ai/run/%s", config.AccountID, selectedModel.Name)
-
-		// Build the request body
-		requestBody := RequestBody{
-			Messages: append([]Message{
-				{Role: "system", Content: "You are a friendly assistant"},
-			}, conversationHistory...),
+		// Run slash commands such as /model or /retry
+		if strings.HasPrefix(userInput, "/") {
+			if err := cv.runCommand(userInput); err != nil {
+				fmt.Println("Error:", err)
+			}
+			continue
 		}
 
-		// Get the assistant's response
-		assistantResponse, err := getAssistantResponse(apiURL, config.Token, requestBody)
-		if err != nil {
-			// If there's an error getting the assistant's response, panic
-			panic(err)
+		// Get the assistant's response, reporting errors and letting the user retry
+		if err := cv.send(userInput); errors.Is(err, context.Canceled) && ctx.Err() == nil {
+			fmt.Println("\nResponse cancelled.")
+		} else if err != nil {
+			fmt.Println("Error getting response:", err)
 		}
-
-		// Print the assistant's response
-		fmt.Printf("\nAssistant's response:\n%s\n", assistantResponse)
-
-		// Add the assistant's response to the conversation history
-		conversationHistory = appendMessage(conversationHistory, "assistant", assistantResponse)
 	}
 }
 
-// promptForConfig prompts the user for the necessary configuration information
-func promptForConfig() auth.Config {
-	var config auth.Config
-	fmt.Print("Enter your Cloudflare Account ID: ")
-	fmt.Scanln(&config.AccountID)
-	fmt.Print("Enter your Cloudflare API Token: ")
-	fmt.Scanln(&config.Token)
-	return config
+// conversation holds the state of a running chat session
+type conversation struct {
+	ctx           context.Context
+	client        *client.Client
+	config        auth.Config
+	model         model.Model      // Model the conversation is held with
+	params        Params           // Generation parameters sent with every message
+	session       *session.Session // Session holding the system prompt and the full transcript
+	historyTokens int              // Explicit history token budget, 0 to derive it from the context window
 }
 
-// appendMessage appends a message to the conversation history
-func appendMessage(history []Message, role, content string) []Message {
-	if len(history) >= maxHistory {
-		// If the conversation history is full, remove the oldest message
-		history = history[1:]
+// history returns the messages sent along with userInput: the transcript followed by
+// userInput, trimmed to a token budget derived from the model's context window unless
+// an explicit budget is requested
+func (cv *conversation) history(userInput string) []Message {
+	budget := cv.historyTokens
+	if budget == 0 {
+		budget = historyBudget(cv.model.ContextWindow(), cv.session.SystemPrompt, cv.params)
 	}
-	return append(history, Message{Role: role, Content: content})
+	messages := append(append([]Message(nil), cv.session.Messages...), Message{Role: "user", Content: userInput})
+	return trimHistory(messages, budget)
 }
 
-// getAssistantResponse makes an API call to the AI API to get the assistant's response
-func getAssistantResponse(url, token string, requestBody RequestBody) (string, error) {
-	// Marshal the request body to JSON
-	body, err := json.Marshal(requestBody)
+// send streams the assistant's response to userInput and records the exchange
+// in the session, persisting it so the session survives quitting or crashing.
+// Ctrl-C cancels the response, which is then left out of the session.
+func (cv *conversation) send(userInput string) error {
+	ctx, stop := signal.NotifyContext(cv.ctx, os.Interrupt)
+	defer stop()
+
+	fmt.Printf("\nAssistant's response:\n")
+	assistantResponse, err := exchange(ctx, cv.client, cv.model.Name, cv.session.SystemPrompt, cv.params, cv.history(userInput))
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	// Create a new request
-	req, err := http.NewRequest("POST", url, bytes.NewBuffer(body))
+	cv.session.Messages = append(cv.session.Messages,
+		Message{Role: "user", Content: userInput},
+		Message{Role: "assistant", Content: assistantResponse},
+	)
+	if err := session.Save(cv.session); err != nil {
+		fmt.Println("Error saving session:", err)
+	}
+	return nil
+}
+
+// OneShot answers a single message without any interaction and writes only the
+// assistant's answer to w. The message is opts.Prompt followed by input, which
+// typically holds text piped to stdin. No tables, banners or prompts are printed.
+func OneShot(ctx context.Context, opts Options, input string, w io.Writer) error {
+	// Load the configuration without prompting, stdin isn't available for answers
+	config, err := auth.LoadConfig()
 	if err != nil {
-		return "", err
+		return fmt.Errorf("failed to load configuration, run \"midai config set\" first: %w", err)
 	}
 
-	// Set the authorization header
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	// Explicit settings win over the persona
+	if err := applyPersona(config, &opts); err != nil {
+		return err
+	}
+	applySettings(&opts)
+	if opts.Model == "" {
+		return fmt.Errorf("a model is required, e.g. -m @cf/meta/llama-3-8b-instruct or \"midai config set text_model <name>\"")
+	}
+	if err := opts.Params.Validate(); err != nil {
+		return err
+	}
 
-	// Make the request
-	client := &http.Client{}
-	res, err := client.Do(req)
+	// Build the user's message from the prompt and the piped input
+	var parts []string
+	for _, part := range []string{opts.Prompt, input} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return fmt.Errorf("nothing to send: provide a prompt or pipe text to stdin")
+	}
+
+	// Build the request body
+	requestBody := RequestBody{
+		Messages: []Message{
+			{Role: "system", Content: opts.SystemPrompt},
+			{Role: "user", Content: strings.Join(parts, "\n\n")},
+		},
+		Params: opts.Params,
+	}
+
+	// Get the assistant's response
+	assistantResponse, err := getAssistantResponse(ctx, client.New(config), opts.Model, requestBody)
 	if err != nil {
-		return "", err
+		return err
 	}
-	defer res.Body.Close()
 
-	// Read the response body
-	respBody, err := io.ReadAll(res.Body)
+	// Write only the answer
+	_, err = fmt.Fprintln(w, strings.TrimSpace(assistantResponse))
+	return err
+}
+
+// applySettings fills the options that are still unset from the settings
+func applySettings(opts *Options) {
+	if opts.Model == "" {
+		opts.Model = settings.String(settings.TextModel)
+	}
+	if opts.SystemPrompt == "" {
+		opts.SystemPrompt = settings.String(settings.SystemPrompt)
+	}
+	if opts.HistoryTokens == 0 {
+		opts.HistoryTokens = settings.Int(settings.HistoryTokens)
+	}
+}
+
+// chooseModel returns the "Text Generation" model matching name, letting the user pick one
+// from a table when name is empty or matches several models, see model.PickModel
+func chooseModel(ctx context.Context, c *client.Client, name string) (model.Model, error) {
+	// Fetch the list of available models
+	models, err := model.GetAvailableModels(ctx, c, model.ListOptions{})
 	if err != nil {
+		if strings.HasPrefix(name, "@") {
+			return model.Model{Name: name}, nil // A full model ID can be used without the catalog
+		}
+		return model.Model{}, err
+	}
+
+	selectedModel, err := model.PickModel(models, "Text Generation", name)
+	if err != nil && name == "" {
+		// Select a random model from the list with the "Text Generation" capability
+		textModels := model.WithTask(models, "Text Generation")
+		if len(textModels) == 0 {
+			return model.Model{}, fmt.Errorf("no models with the 'Text Generation' capability available")
+		}
+		randomIndex := rand.Intn(len(textModels)) // Generate a random index within the range of models
+		selectedModel = textModels[randomIndex]   // Select the model at that index
+		fmt.Printf("\nWe select the \"%s\" for you.\n", path.Base(selectedModel.Name))
+		return selectedModel, nil
+	}
+	return selectedModel, err
+}
+
+// lookupModel returns the catalog entry of the named model, which carries properties
+// such as its context window. If the catalog can't be fetched or doesn't list the
+// model, a model with just the name is returned.
+func lookupModel(ctx context.Context, c *client.Client, name string) model.Model {
+	models, err := model.GetAvailableModels(ctx, c, model.ListOptions{})
+	if err == nil {
+		for _, m := range models {
+			if m.Name == name {
+				return m
+			}
+		}
+	}
+	return model.Model{Name: name}
+}
+
+// exchange sends the conversation history to the model and streams the
+// assistant's response to stdout, returning the fully assembled response
+func exchange(ctx context.Context, c *client.Client, modelName, systemPrompt string, params Params, history []Message) (string, error) {
+	// Reject out of range parameters before sending anything
+	if err := params.Validate(); err != nil {
 		return "", err
 	}
 
-	// Unmarshal the response body to the ApiResponse struct
-	var apiResponse ApiResponse
-	if err := json.Unmarshal(respBody, &apiResponse); err != nil {
+	// Build the request body
+	requestBody := RequestBody{
+		Messages: append([]Message{
+			{Role: "system", Content: systemPrompt},
+		}, history...),
+		Stream: true,
+		Params: params,
+	}
+
+	// Stream the assistant's response, printing tokens as they arrive
+	assistantResponse, err := streamAssistantResponse(ctx, c, modelName, requestBody, func(token string) {
+		fmt.Print(token)
+	})
+	fmt.Println()
+	return assistantResponse, err
+}
+
+// getAssistantResponse makes an API call to the AI API to get the assistant's response
+func getAssistantResponse(ctx context.Context, c *client.Client, modelName string, requestBody RequestBody) (string, error) {
+	var result ApiResponse
+	if err := c.Run(ctx, modelName, requestBody, &result); err != nil {
 		return "", err
 	}
 
 	// Return the assistant's response
-	return apiResponse.Result.Response, nil
+	return result.Response, nil
+}
+
+// streamAssistantResponse makes a streaming API call to the AI API. Every token is
+// passed to onToken as soon as its server-sent event frame arrives, and the fully
+// assembled response is returned once the stream ends.
+func streamAssistantResponse(ctx context.Context, c *client.Client, modelName string, requestBody RequestBody, onToken func(string)) (string, error) {
+	// Make sure the API streams the response
+	requestBody.Stream = true
+
+	var response strings.Builder
+	err := c.Stream(ctx, modelName, requestBody, func(data []byte) error {
+		// Unmarshal the frame and hand its token to the caller
+		var frame ApiResponse
+		if err := json.Unmarshal(data, &frame); err != nil {
+			return fmt.Errorf("failed to parse stream frame: %w\nRaw Frame: %s", err, string(data))
+		}
+		if frame.Response == "" {
+			return nil
+		}
+		response.WriteString(frame.Response)
+		if onToken != nil {
+			onToken(frame.Response)
+		}
+		return nil
+	})
+
+	// Return the assembled response, which is partial if the stream failed
+	return response.String(), err
 }
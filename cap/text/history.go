@@ -0,0 +1,65 @@
+package gentext
+
+import (
+	"unicode/utf8"
+)
+
+const (
+	// defaultContextWindow is assumed when the model catalog doesn't report a context window
+	defaultContextWindow = 4096
+	// defaultMaxTokens is the number of tokens Workers AI generates when max_tokens isn't set
+	defaultMaxTokens = 256
+	// minHistoryBudget keeps at least some history even for small context windows
+	minHistoryBudget = 256
+	// messageOverhead approximates the tokens a chat template adds around every message
+	messageOverhead = 4
+)
+
+// estimateTokens roughly estimates the number of tokens in text, assuming about
+// four characters per token as is typical for English text and code
+func estimateTokens(text string) int {
+	return (utf8.RuneCountInString(text)+3)/4 + messageOverhead
+}
+
+// historyBudget returns the number of tokens the conversation history may use: the
+// context window minus the system prompt and the tokens reserved for the answer
+func historyBudget(contextWindow int, systemPrompt string, params Params) int {
+	if contextWindow <= 0 {
+		contextWindow = defaultContextWindow
+	}
+	reserved := defaultMaxTokens
+	if params.MaxTokens != nil {
+		reserved = *params.MaxTokens
+	}
+
+	budget := contextWindow - reserved - estimateTokens(systemPrompt)
+	if budget < minHistoryBudget {
+		budget = minHistoryBudget
+	}
+	return budget
+}
+
+// trimHistory drops the oldest messages until the estimated size of history fits
+// into budget. A user message is always dropped together with the assistant reply
+// that follows it, and the latest message is always kept. The system prompt isn't
+// part of history, so it's never dropped.
+func trimHistory(history []Message, budget int) []Message {
+	total := 0
+	for _, m := range history {
+		total += estimateTokens(m.Content)
+	}
+
+	start := 0
+	for total > budget && start < len(history)-1 {
+		// Drop the oldest message along with the reply to it
+		end := start + 1
+		if history[start].Role == "user" && end < len(history)-1 && history[end].Role == "assistant" {
+			end++
+		}
+		for _, m := range history[start:end] {
+			total -= estimateTokens(m.Content)
+		}
+		start = end
+	}
+	return history[start:]
+}
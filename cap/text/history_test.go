@@ -0,0 +1,117 @@
+package gentext
+
+import (
+	"strings"
+	"testing"
+)
+
+// msg creates a message whose estimated size is 5 tokens: one for its four
+// characters plus the per-message overhead
+func msg(role, content string) Message {
+	return Message{Role: role, Content: content}
+}
+
+// roles returns the roles and contents of messages, e.g. "user:u1 assistant:a1"
+func roles(messages []Message) string {
+	parts := make([]string, len(messages))
+	for i, m := range messages {
+		parts[i] = m.Role + ":" + m.Content
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestTrimHistory(t *testing.T) {
+	conversation := []Message{
+		msg("user", "u1.."), msg("assistant", "a1.."),
+		msg("user", "u2.."), msg("assistant", "a2.."),
+		msg("user", "u3.."),
+	}
+	tests := []struct {
+		name    string
+		history []Message
+		budget  int
+		want    string
+	}{
+		{
+			name:    "fits",
+			history: conversation,
+			budget:  25,
+			want:    "user:u1.. assistant:a1.. user:u2.. assistant:a2.. user:u3..",
+		},
+		{
+			name:    "drops the oldest pair",
+			history: conversation,
+			budget:  24,
+			want:    "user:u2.. assistant:a2.. user:u3..",
+		},
+		{
+			name:    "drops a user message together with its reply",
+			history: conversation,
+			budget:  14, // "assistant:a2.. user:u3.." would fit, but the reply must not be orphaned
+			want:    "user:u3..",
+		},
+		{
+			name:    "keeps the latest message over budget",
+			history: conversation,
+			budget:  0,
+			want:    "user:u3..",
+		},
+		{
+			name:    "starts with an assistant message",
+			history: []Message{msg("assistant", "a0.."), msg("user", "u1.."), msg("assistant", "a1.."), msg("user", "u2..")},
+			budget:  15,
+			want:    "user:u1.. assistant:a1.. user:u2..",
+		},
+		{
+			name:    "starts with an assistant message over budget",
+			history: []Message{msg("assistant", "a0.."), msg("user", "u1.."), msg("assistant", "a1.."), msg("user", "u2..")},
+			budget:  10,
+			want:    "user:u2..",
+		},
+		{
+			name:    "consecutive user messages",
+			history: []Message{msg("user", "u1.."), msg("user", "u2.."), msg("assistant", "a2.."), msg("user", "u3..")},
+			budget:  15,
+			want:    "user:u2.. assistant:a2.. user:u3..",
+		},
+		{
+			name:    "empty",
+			history: nil,
+			budget:  0,
+			want:    "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := trimHistory(tt.history, tt.budget)
+			if roles(got) != tt.want {
+				t.Errorf("trimHistory(budget %d) = %q, want %q", tt.budget, roles(got), tt.want)
+			}
+			if len(got) > 0 && got[0].Role == "assistant" && tt.history[0].Role != "assistant" {
+				t.Errorf("trimHistory(budget %d) starts with an orphaned assistant reply", tt.budget)
+			}
+		})
+	}
+}
+
+func TestHistoryBudget(t *testing.T) {
+	maxTokens := 1000
+	tests := []struct {
+		name          string
+		contextWindow int
+		systemPrompt  string
+		params        Params
+		want          int
+	}{
+		{name: "default answer size", contextWindow: 8192, want: 8192 - defaultMaxTokens - messageOverhead},
+		{name: "explicit answer size", contextWindow: 8192, params: Params{MaxTokens: &maxTokens}, want: 8192 - 1000 - messageOverhead},
+		{name: "system prompt", contextWindow: 8192, systemPrompt: strings.Repeat("x", 400), want: 8192 - defaultMaxTokens - 100 - messageOverhead},
+		{name: "unknown context window", contextWindow: 0, want: defaultContextWindow - defaultMaxTokens - messageOverhead},
+		{name: "tiny context window", contextWindow: 300, want: minHistoryBudget},
+	}
+	for _, tt := range tests {
+		if got := historyBudget(tt.contextWindow, tt.systemPrompt, tt.params); got != tt.want {
+			t.Errorf("historyBudget(%s) = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package gentext
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Params holds the optional generation parameters sent along with the messages.
+// Nil fields are omitted so the model's defaults apply.
+type Params struct {
+	Temperature       *float64 `json:"temperature,omitempty"`        // Randomness of the output, 0 to 5
+	MaxTokens         *int     `json:"max_tokens,omitempty"`         // Maximum number of tokens to generate, at least 1
+	TopP              *float64 `json:"top_p,omitempty"`              // Nucleus sampling probability mass, 0.001 to 1
+	TopK              *int     `json:"top_k,omitempty"`              // Number of most likely tokens sampled from, 1 to 50
+	Seed              *int64   `json:"seed,omitempty"`               // Random seed for reproducible output, 1 to 9999999999
+	RepetitionPenalty *float64 `json:"repetition_penalty,omitempty"` // Penalty for repeated tokens, 0 to 2
+	FrequencyPenalty  *float64 `json:"frequency_penalty,omitempty"`  // Penalty growing with token frequency, -2 to 2
+	PresencePenalty   *float64 `json:"presence_penalty,omitempty"`   // Penalty for tokens already present, -2 to 2
+}
+
+// ParamNames lists the names accepted by Params.Set, in display order
+var ParamNames = []string{"temperature", "max_tokens", "top_p", "top_k", "seed", "repetition_penalty", "frequency_penalty", "presence_penalty"}
+
+// merge returns p with every field that is set in override replaced by its value
+func (p Params) merge(override Params) Params {
+	if override.Temperature != nil {
+		p.Temperature = override.Temperature
+	}
+	if override.MaxTokens != nil {
+		p.MaxTokens = override.MaxTokens
+	}
+	if override.TopP != nil {
+		p.TopP = override.TopP
+	}
+	if override.TopK != nil {
+		p.TopK = override.TopK
+	}
+	if override.Seed != nil {
+		p.Seed = override.Seed
+	}
+	if override.RepetitionPenalty != nil {
+		p.RepetitionPenalty = override.RepetitionPenalty
+	}
+	if override.FrequencyPenalty != nil {
+		p.FrequencyPenalty = override.FrequencyPenalty
+	}
+	if override.PresencePenalty != nil {
+		p.PresencePenalty = override.PresencePenalty
+	}
+	return p
+}
+
+// Validate checks that every parameter that is set lies within the range Workers AI accepts
+func (p Params) Validate() error {
+	checkFloat := func(name string, v *float64, min, max float64) error {
+		if v != nil && (*v < min || *v > max) {
+			return fmt.Errorf("%s must be between %g and %g, got %g", name, min, max, *v)
+		}
+		return nil
+	}
+	checkInt := func(name string, v *int64, min, max int64) error {
+		if v != nil && (*v < min || *v > max) {
+			return fmt.Errorf("%s must be between %d and %d, got %d", name, min, max, *v)
+		}
+		return nil
+	}
+	int64Ptr := func(v *int) *int64 {
+		if v == nil {
+			return nil
+		}
+		n := int64(*v)
+		return &n
+	}
+
+	for _, err := range []error{
+		checkFloat("temperature", p.Temperature, 0, 5),
+		checkInt("max_tokens", int64Ptr(p.MaxTokens), 1, 1<<31-1),
+		checkFloat("top_p", p.TopP, 0.001, 1),
+		checkInt("top_k", int64Ptr(p.TopK), 1, 50),
+		checkInt("seed", p.Seed, 1, 9999999999),
+		checkFloat("repetition_penalty", p.RepetitionPenalty, 0, 2),
+		checkFloat("frequency_penalty", p.FrequencyPenalty, -2, 2),
+		checkFloat("presence_penalty", p.PresencePenalty, -2, 2),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Set parses value and assigns it to the parameter with the given name.
+// Names may use dashes instead of underscores; an empty value or "default" unsets the parameter.
+// The result is validated, leaving p unchanged if it's out of range.
+func (p *Params) Set(name, value string) error {
+	name = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "-", "_")
+	value = strings.TrimSpace(value)
+	unset := value == "" || value == "default"
+
+	parseFloat := func(dst **float64) error {
+		if unset {
+			*dst = nil
+			return nil
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("%s must be a number, got %q", name, value)
+		}
+		*dst = &v
+		return nil
+	}
+	parseInt := func(dst **int) error {
+		if unset {
+			*dst = nil
+			return nil
+		}
+		v, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%s must be an integer, got %q", name, value)
+		}
+		*dst = &v
+		return nil
+	}
+
+	updated := *p
+	var err error
+	switch name {
+	case "temperature":
+		err = parseFloat(&updated.Temperature)
+	case "max_tokens":
+		err = parseInt(&updated.MaxTokens)
+	case "top_p":
+		err = parseFloat(&updated.TopP)
+	case "top_k":
+		err = parseInt(&updated.TopK)
+	case "seed":
+		if unset {
+			updated.Seed = nil
+			break
+		}
+		v, parseErr := strconv.ParseInt(value, 10, 64)
+		if parseErr != nil {
+			return fmt.Errorf("seed must be an integer, got %q", value)
+		}
+		updated.Seed = &v
+	case "repetition_penalty":
+		err = parseFloat(&updated.RepetitionPenalty)
+	case "frequency_penalty":
+		err = parseFloat(&updated.FrequencyPenalty)
+	case "presence_penalty":
+		err = parseFloat(&updated.PresencePenalty)
+	default:
+		return fmt.Errorf("unknown parameter %q, available: %s", name, strings.Join(ParamNames, ", "))
+	}
+	if err != nil {
+		return err
+	}
+	if err := updated.Validate(); err != nil {
+		return err
+	}
+	*p = updated
+	return nil
+}
+
+// String lists the parameters that are set as "name=value" pairs, or "defaults" if none are
+func (p Params) String() string {
+	var parts []string
+	addFloat := func(name string, v *float64) {
+		if v != nil {
+			parts = append(parts, fmt.Sprintf("%s=%g", name, *v))
+		}
+	}
+	addInt := func(name string, v *int) {
+		if v != nil {
+			parts = append(parts, fmt.Sprintf("%s=%d", name, *v))
+		}
+	}
+
+	addFloat("temperature", p.Temperature)
+	addInt("max_tokens", p.MaxTokens)
+	addFloat("top_p", p.TopP)
+	addInt("top_k", p.TopK)
+	if p.Seed != nil {
+		parts = append(parts, fmt.Sprintf("seed=%d", *p.Seed))
+	}
+	addFloat("repetition_penalty", p.RepetitionPenalty)
+	addFloat("frequency_penalty", p.FrequencyPenalty)
+	addFloat("presence_penalty", p.PresencePenalty)
+
+	if len(parts) == 0 {
+		return "defaults"
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,59 @@
+package gentext
+
+import (
+	"MidAI/auth"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// lookupPersona returns the named persona from the configuration along with its parameters
+func lookupPersona(config auth.Config, name string) (auth.Persona, Params, error) {
+	persona, ok := config.Personas[name]
+	if !ok {
+		return auth.Persona{}, Params{}, fmt.Errorf("persona %q not found, available: %s", name, personaNames(config))
+	}
+
+	// Decode the generation parameters of the persona
+	var params Params
+	if len(persona.Params) > 0 {
+		if err := json.Unmarshal(persona.Params, &params); err != nil {
+			return auth.Persona{}, Params{}, fmt.Errorf("invalid parameters for persona %q: %w", name, err)
+		}
+	}
+	return persona, params, nil
+}
+
+// applyPersona fills the settings of opts that weren't given explicitly from the persona named by opts.Persona
+func applyPersona(config auth.Config, opts *Options) error {
+	if opts.Persona == "" {
+		return nil
+	}
+
+	persona, params, err := lookupPersona(config, opts.Persona)
+	if err != nil {
+		return err
+	}
+	if opts.SystemPrompt == "" {
+		opts.SystemPrompt = persona.SystemPrompt
+	}
+	if opts.Model == "" {
+		opts.Model = persona.Model
+	}
+	opts.Params = params.merge(opts.Params)
+	return nil
+}
+
+// personaNames returns the sorted, comma separated names of the configured personas
+func personaNames(config auth.Config) string {
+	if len(config.Personas) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(config.Personas))
+	for name := range config.Personas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
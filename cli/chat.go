@@ -0,0 +1,58 @@
+package cli
+
+import (
+	gentext "MidAI/cap/text"
+	"context"
+	"fmt"
+	"io"
+	"os"
+)
+
+// runChat implements "midai chat"
+func runChat(args []string) error {
+	var opts gentext.Options
+	fs := newFlagSet("chat", "[prompt]")
+	fs.StringVar(&opts.Model, "model", "", "name of the text generation model, e.g. @cf/meta/llama-3-8b-instruct")
+	fs.StringVar(&opts.Model, "m", "", "shorthand for -model")
+	fs.StringVar(&opts.Prompt, "prompt", "", "message to send; answers once instead of starting a conversation")
+	fs.StringVar(&opts.Prompt, "p", "", "shorthand for -prompt")
+	fs.IntVar(&opts.HistoryTokens, "history-tokens", 0, "token budget of the conversation history; derived from the model's context window by default")
+	fs.StringVar(&opts.SystemPrompt, "system", "", "system prompt sent ahead of the conversation")
+	fs.StringVar(&opts.SystemPrompt, "s", "", "shorthand for -system")
+	fs.StringVar(&opts.Persona, "persona", "", "name of a persona from the config file")
+	fs.StringVar(&opts.Resume, "resume", "", "ID (or unique ID prefix) of a saved session to continue")
+	fs.BoolVar(&opts.Refresh, "refresh", false, "fetch the model catalog even if the cached one is still fresh")
+	addParamFlags(fs, &opts.Params)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	// Positional arguments form the prompt when -prompt isn't given
+	if opts.Prompt == "" {
+		opts.Prompt = joinArgs(fs)
+	}
+
+	// Text piped to stdin is sent along with the prompt and only the answer is printed
+	input, piped, err := readPipedStdin()
+	if err != nil {
+		return err
+	}
+	if piped {
+		return gentext.OneShot(context.Background(), opts, input, os.Stdout)
+	}
+	return gentext.Chat(context.Background(), opts)
+}
+
+// readPipedStdin reads all of stdin if it is a pipe or a file rather than a terminal
+func readPipedStdin() (string, bool, error) {
+	info, err := os.Stdin.Stat()
+	if err != nil || info.Mode()&os.ModeCharDevice != 0 {
+		return "", false, nil
+	}
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", true, fmt.Errorf("failed to read stdin: %w", err)
+	}
+	return string(data), true, nil
+}
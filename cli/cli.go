@@ -0,0 +1,236 @@
+package cli
+
+import (
+	"MidAI/auth"
+	"MidAI/client"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// usage is printed by "midai help" and whenever the command line can't be parsed
+const usage = `Usage: midai [--profile <name>] [--no-validate] [--debug] <command> [flags] [arguments]
+
+Run midai without arguments to start the interactive menu.
+
+Credentials are read from the CLOUDFLARE_ACCOUNT_ID and CLOUDFLARE_API_TOKEN
+environment variables, the profile's token_command or the config file, in that order.
+
+Global flags:
+  --profile <name>
+                 Credential profile to use instead of the default one,
+                 also settable with the MIDAI_PROFILE environment variable
+  --no-validate  Send model inputs without checking them against the model's
+                 input schema first
+  --debug        Log details such as retried requests to stderr
+
+Commands:
+  chat           Chat with a text generation model
+  image          Generate images with a text-to-image model
+  models list    List the models available to your account
+  models show <name>
+                 Show a model's properties and its input and output schema
+  personas list  List the personas defined in the config file
+  personas set <name>
+                 Define a persona with a system prompt, model and parameters
+  personas remove <name>
+                 Remove a persona
+  sessions list  List saved chat sessions
+  sessions delete <id>...
+                 Delete saved chat sessions
+  sessions prune Delete chat sessions that haven't been used for a while
+  config set     Save your Cloudflare Account ID and API Token
+  config set <key> <value>
+                 Save a setting such as text_model or output_dir
+  config get <key>
+                 Show the value of a setting
+  config unset <key>
+                 Remove a setting from the settings file
+  config list    Show all settings with their values and where they come from
+  config show    Show the saved configuration
+  config whoami  Show which credentials are used and where they come from
+  config verify  Check that the API Token is active and has Workers AI access
+  config encrypt Encrypt the saved API Token with a passphrase
+  config decrypt Store the saved API Token in plaintext again
+  config paths   Show where configuration, sessions, images and caches are stored
+  profiles list  List the credential profiles
+  profiles add <name>
+                 Add a credential profile
+  profiles rename <old-name> <new-name>
+                 Rename a credential profile
+  profiles remove <name>
+                 Remove a credential profile
+  profiles use <name>
+                 Make a credential profile the default
+  help           Show this help
+
+Run "midai <command> -h" for the flags of a command.
+`
+
+// errUsage reports an invalid command line; the usage has already been printed
+var errUsage = errors.New("invalid usage")
+
+// Run executes the subcommand named by args[0] and returns the process exit code
+func Run(args []string) int {
+	err := dispatch(args)
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	default:
+		fmt.Fprintln(os.Stderr, "midai:", err)
+		return 1
+	}
+}
+
+// dispatch parses the global flags and runs the subcommand that follows them
+func dispatch(args []string) error {
+	global := flag.NewFlagSet("midai", flag.ContinueOnError)
+	global.StringVar(&auth.ActiveProfile, "profile", "", "credential profile to use")
+	global.BoolVar(&client.SkipValidation, "no-validate", false, "don't validate model inputs")
+	debug := global.Bool("debug", false, "log debug details")
+	global.SetOutput(io.Discard)
+	if err := global.Parse(args); errors.Is(err, flag.ErrHelp) {
+		fmt.Print(usage)
+		return nil
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "midai: %v\n\n%s", err, usage)
+		return errUsage
+	}
+	if *debug {
+		slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	}
+	args = global.Args()
+
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return errUsage
+	}
+
+	switch args[0] {
+	case "chat":
+		return runChat(args[1:])
+	case "image":
+		return runImage(args[1:])
+	case "models":
+		return runGroup("models", args[1:], map[string]func([]string) error{
+			"list": runModelsList,
+			"show": runModelsShow,
+		})
+	case "profiles":
+		return runGroup("profiles", args[1:], map[string]func([]string) error{
+			"list":   runProfilesList,
+			"add":    runProfilesAdd,
+			"rename": runProfilesRename,
+			"remove": runProfilesRemove,
+			"use":    runProfilesUse,
+		})
+	case "personas":
+		return runGroup("personas", args[1:], map[string]func([]string) error{
+			"list":   runPersonasList,
+			"set":    runPersonasSet,
+			"remove": runPersonasRemove,
+		})
+	case "sessions":
+		return runGroup("sessions", args[1:], map[string]func([]string) error{
+			"list":   runSessionsList,
+			"delete": runSessionsDelete,
+			"prune":  runSessionsPrune,
+		})
+	case "config":
+		return runGroup("config", args[1:], map[string]func([]string) error{
+			"set":     runConfigSet,
+			"get":     runConfigGet,
+			"unset":   runConfigUnset,
+			"list":    runConfigList,
+			"show":    runConfigShow,
+			"whoami":  runConfigWhoami,
+			"verify":  runConfigVerify,
+			"encrypt": runConfigEncrypt,
+			"decrypt": runConfigDecrypt,
+			"paths":   runConfigPaths,
+		})
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+		return nil
+	default:
+		fmt.Fprintf(os.Stderr, "midai: unknown command %q\n\n%s", args[0], usage)
+		return errUsage
+	}
+}
+
+// runGroup runs the subcommand of a command group such as "models" or "config"
+func runGroup(group string, args []string, subcommands map[string]func([]string) error) error {
+	if len(args) > 0 {
+		if run, ok := subcommands[args[0]]; ok {
+			return run(args[1:])
+		}
+		fmt.Fprintf(os.Stderr, "midai: unknown command %q\n\n", group+" "+args[0])
+	}
+	fmt.Fprint(os.Stderr, usage)
+	return errUsage
+}
+
+// newFlagSet creates the flag set of a subcommand with a usage line listing its arguments
+func newFlagSet(name, arguments string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: midai %s [flags] %s\n\nFlags:\n", name, arguments)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses the flags of a subcommand, mapping parse errors to errUsage.
+// Flags may follow positional arguments, e.g. "midai personas set reviewer -system ...";
+// everything after a "--" is taken as positional.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	fs.SetOutput(io.Discard)
+	err := parseInterspersed(fs, args)
+	fs.SetOutput(os.Stderr)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, flag.ErrHelp):
+		fs.Usage()
+		return err
+	default:
+		fmt.Fprintf(os.Stderr, "midai %s: %v\n", fs.Name(), err)
+		fs.Usage()
+		return errUsage
+	}
+}
+
+// parseInterspersed parses args, collecting positional arguments found between flags
+func parseInterspersed(fs *flag.FlagSet, args []string) error {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+
+		// Stop once everything is parsed or a "--" ended flag parsing
+		consumed := len(args) - fs.NArg()
+		if fs.NArg() == 0 || (consumed > 0 && args[consumed-1] == "--") {
+			positional = append(positional, fs.Args()...)
+			break
+		}
+
+		// Keep the positional argument and continue with the flags that follow it
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	// Parse the collected positional arguments so fs.Args returns them
+	return fs.Parse(append([]string{"--"}, positional...))
+}
+
+// joinArgs joins the positional arguments of a subcommand into a single prompt
+func joinArgs(fs *flag.FlagSet) string {
+	return strings.TrimSpace(strings.Join(fs.Args(), " "))
+}
@@ -0,0 +1,278 @@
+package cli
+
+import (
+	"MidAI/auth"
+	"MidAI/client"
+	"MidAI/paths"
+	"MidAI/session"
+	"MidAI/settings"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// runConfigSet implements "midai config set"
+func runConfigSet(args []string) error {
+	var accountID, token, tokenCommand string
+	var encrypt, force bool
+	fs := newFlagSet("config set", "[key value]")
+	fs.StringVar(&accountID, "account-id", "", "Cloudflare Account ID")
+	fs.StringVar(&token, "token", "", "Cloudflare API Token with Workers AI Read and Edit access")
+	fs.StringVar(&tokenCommand, "token-command", "", `command printing the API Token, e.g. "pass show cloudflare"; "none" removes it`)
+	fs.BoolVar(&encrypt, "encrypt", false, "store the API Token encrypted with a passphrase, reading it from the prompt or stdin")
+	fs.BoolVar(&force, "force", false, "save the credentials even if they can't be verified")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	// Positional arguments set a setting instead of the credentials
+	if fs.NArg() > 0 {
+		if fs.NArg() != 2 || fs.NFlag() > 0 {
+			fs.Usage()
+			return errUsage
+		}
+		return setSetting(fs.Arg(0), fs.Arg(1))
+	}
+
+	// A token to encrypt is read without echoing it, so it doesn't end up in the
+	// process list or the shell history either
+	if encrypt {
+		if token != "" {
+			return fmt.Errorf("-token can't be combined with -encrypt, enter the token at the prompt or pipe it to stdin instead")
+		}
+		var err error
+		if token, err = auth.ReadToken(); err != nil {
+			return err
+		}
+		if token == "" {
+			return fmt.Errorf("no API Token given")
+		}
+	}
+
+	// Start from the saved profile so a single field can be updated. The file is
+	// used directly so credentials from the environment are never written to it.
+	file, err := auth.LoadFile()
+	if err != nil && !errors.Is(err, auth.ErrConfigNotExist) {
+		return err
+	}
+	name := file.ActiveProfileName()
+	profile := file.Profiles[name]
+	if accountID != "" {
+		profile.AccountID = accountID
+	}
+	if token != "" && tokenCommand != "" && tokenCommand != "none" {
+		return fmt.Errorf("-token and -token-command can't be used together, the command would take precedence")
+	}
+	if token != "" {
+		// A token command takes precedence over the token, so it would hide the new one
+		if profile.TokenCommand != "" {
+			fmt.Printf("Removing the token command %q of profile %q in favor of the new token.\n", profile.TokenCommand, name)
+		}
+		// Keep an encrypted token encrypted, "midai config decrypt" turns that off explicitly
+		if profile.EncryptedToken != nil && !encrypt {
+			fmt.Println("The current API Token is encrypted, the new one is encrypted as well.")
+			encrypt = true
+		}
+		profile.Token, profile.EncryptedToken, profile.TokenCommand = token, nil, ""
+	}
+	switch tokenCommand {
+	case "":
+	case "none":
+		profile.TokenCommand = ""
+	default:
+		profile.TokenCommand = tokenCommand
+	}
+
+	// Prompt for the credentials that are still missing
+	if profile.AccountID == "" || !profile.HasToken() {
+		profile = auth.PromptForProfile(profile)
+	}
+	if profile.AccountID == "" || !profile.HasToken() {
+		return fmt.Errorf("config fields cannot be empty")
+	}
+
+	// Refuse to save credentials that don't work
+	if err := verifyProfile(name, profile, force); err != nil {
+		return err
+	}
+
+	// Replace the plaintext token by its encrypted form if requested
+	if encrypt {
+		if err := encryptProfileToken(&profile); err != nil {
+			return err
+		}
+	}
+
+	file.SetProfile(name, profile)
+	if err := auth.SaveFile(file); err != nil {
+		return err
+	}
+	fmt.Printf("Profile %q saved to %s\n", name, auth.ConfigFile)
+	return nil
+}
+
+// runConfigShow implements "midai config show"
+func runConfigShow(args []string) error {
+	fs := newFlagSet("config show", "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	config, err := auth.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Config file: %s\n", auth.ConfigFile)
+	fmt.Printf("Profile:     %s\n", config.Profile)
+	fmt.Printf("Account ID:  %s\n", config.AccountID)
+	fmt.Printf("API Token:   %s\n", maskToken(config.Token))
+	return nil
+}
+
+// runConfigPaths implements "midai config paths"
+func runConfigPaths(args []string) error {
+	fs := newFlagSet("config paths", "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	fmt.Printf("Config file: %s\n", auth.ConfigFile)
+	fmt.Printf("Settings:    %s\n", settings.File)
+	fmt.Printf("Sessions:    %s\n", session.Dir)
+	fmt.Printf("Images:      %s\n", settings.String(settings.OutputDir))
+	fmt.Printf("Cache:       %s\n", paths.CacheDir)
+	return nil
+}
+
+// runConfigVerify implements "midai config verify"
+func runConfigVerify(args []string) error {
+	fs := newFlagSet("config verify", "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	config, err := auth.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Verifying profile %q...\n", config.Profile)
+	v := client.New(config).Verify(context.Background())
+	fmt.Print(v.Report())
+	return v.Err()
+}
+
+// verifyProfile checks that the credentials of a profile are active and have Workers AI
+// access, printing a report. Unless force is set, a failed check is returned as an error.
+func verifyProfile(name string, profile auth.Profile, force bool) error {
+	config, err := auth.ProfileConfig(name, profile)
+	if err == nil {
+		fmt.Println("Verifying credentials...")
+		v := client.New(config).Verify(context.Background())
+		fmt.Print(v.Report())
+		err = v.Err()
+	}
+	if err != nil && !force {
+		return fmt.Errorf("%w; use --force to save the credentials anyway", err)
+	}
+	return nil
+}
+
+// runConfigWhoami implements "midai config whoami"
+func runConfigWhoami(args []string) error {
+	fs := newFlagSet("config whoami", "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	config, err := auth.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Profile:     %s\n", config.Profile)
+	fmt.Printf("Account ID:  %s\n", config.AccountID)
+	fmt.Printf("  from       %s\n", config.AccountIDSource)
+	fmt.Printf("API Token:   %s\n", maskToken(config.Token))
+	fmt.Printf("  from       %s\n", config.TokenSource)
+	return nil
+}
+
+// runConfigEncrypt implements "midai config encrypt"
+func runConfigEncrypt(args []string) error {
+	fs := newFlagSet("config encrypt", "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	return updateActiveProfile(encryptProfileToken, "API Token encrypted.")
+}
+
+// runConfigDecrypt implements "midai config decrypt"
+func runConfigDecrypt(args []string) error {
+	fs := newFlagSet("config decrypt", "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	return updateActiveProfile(func(profile *auth.Profile) error {
+		if profile.EncryptedToken == nil {
+			return fmt.Errorf("the API Token is not encrypted")
+		}
+		passphrase, err := auth.ReadPassphrase("Passphrase: ", false)
+		if err != nil {
+			return err
+		}
+		token, err := profile.EncryptedToken.Decrypt(passphrase)
+		if err != nil {
+			return err
+		}
+		profile.Token, profile.EncryptedToken = token, nil
+		return nil
+	}, "API Token stored in plaintext.")
+}
+
+// encryptProfileToken replaces the plaintext token of profile by its encrypted form
+func encryptProfileToken(profile *auth.Profile) error {
+	if profile.EncryptedToken != nil {
+		return fmt.Errorf("the API Token is already encrypted")
+	}
+	if profile.Token == "" {
+		return fmt.Errorf("there is no API Token to encrypt")
+	}
+
+	passphrase, err := auth.ReadPassphrase("New passphrase: ", true)
+	if err != nil {
+		return err
+	}
+	encrypted, err := auth.EncryptToken(profile.Token, passphrase)
+	if err != nil {
+		return err
+	}
+	profile.Token, profile.EncryptedToken = "", encrypted
+	return nil
+}
+
+// updateActiveProfile applies change to the active profile, saves the config file and prints done
+func updateActiveProfile(change func(profile *auth.Profile) error, done string) error {
+	return updateProfiles(func(file *auth.File) error {
+		name := file.ActiveProfileName()
+		profile, ok := file.Profiles[name]
+		if !ok {
+			return fmt.Errorf("%w: %q", auth.ErrProfileNotExist, name)
+		}
+		if err := change(&profile); err != nil {
+			return err
+		}
+		file.SetProfile(name, profile)
+		return nil
+	}, done)
+}
+
+// maskToken hides all but the last four characters of a token
+func maskToken(token string) string {
+	if len(token) <= 4 {
+		return strings.Repeat("*", len(token))
+	}
+	return strings.Repeat("*", len(token)-4) + token[len(token)-4:]
+}
@@ -0,0 +1,28 @@
+package cli
+
+import (
+	genimg "MidAI/cap/image"
+	"context"
+)
+
+// runImage implements "midai image"
+func runImage(args []string) error {
+	var opts genimg.Options
+	fs := newFlagSet("image", "[prompt]")
+	fs.StringVar(&opts.Model, "model", "", "name of the text-to-image model, e.g. @cf/black-forest-labs/flux-1-schnell")
+	fs.StringVar(&opts.Model, "m", "", "shorthand for -model")
+	fs.StringVar(&opts.Prompt, "prompt", "", "image description; generates one image instead of starting the prompt loop")
+	fs.StringVar(&opts.Prompt, "p", "", "shorthand for -prompt")
+	fs.StringVar(&opts.Output, "output", "", "path the generated image is saved to (default: a new file in the output_dir setting)")
+	fs.StringVar(&opts.Output, "o", "", "shorthand for -output")
+	fs.BoolVar(&opts.Refresh, "refresh", false, "fetch the model catalog even if the cached one is still fresh")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	// Positional arguments form the prompt when -prompt isn't given
+	if opts.Prompt == "" {
+		opts.Prompt = joinArgs(fs)
+	}
+	return genimg.Generate(context.Background(), opts)
+}
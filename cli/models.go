@@ -0,0 +1,183 @@
+package cli
+
+import (
+	"MidAI/client"
+	model "MidAI/models"
+	"MidAI/table"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// runModelsList implements "midai models list"
+func runModelsList(args []string) error {
+	var opts model.ListOptions
+	var format string
+	fs := newFlagSet("models list", "[search]")
+	fs.StringVar(&opts.Search, "search", "", "only list models whose name or description contains this text")
+	fs.StringVar(&opts.Task, "task", "", `only list models with this task, e.g. "Text Generation" or "Text-to-Image"`)
+	fs.StringVar(&opts.Author, "author", "", `only list models by this author, e.g. "meta"`)
+	fs.IntVar(&opts.Source, "source", 0, "only list models from the source with this ID")
+	fs.BoolVar(&opts.HideExperimental, "hide-experimental", false, "leave out experimental models")
+	fs.BoolVar(&opts.Refresh, "refresh", false, "fetch the model catalog even if the cached one is still fresh")
+	addOutputFlag(fs, &format)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	// Positional arguments form the search text when -search isn't given
+	if opts.Search == "" {
+		opts.Search = joinArgs(fs)
+	}
+
+	// Create the API client, setting up the configuration first if necessary
+	ctx := context.Background()
+	c, err := client.LoadOrSetup(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Fetch the list of available models
+	models, err := model.GetAvailableModels(ctx, c, opts)
+	if err != nil {
+		return err
+	}
+
+	// Print the models in the requested format; all but the table hold every field untruncated
+	if models == nil {
+		models = []model.Model{} // Print an empty array rather than null
+	}
+	return output{
+		value: models,
+		rows: func() [][]string {
+			rows := [][]string{{"id", "name", "source", "task", "description", "properties"}}
+			for _, m := range models {
+				properties, _ := json.Marshal(m.Properties)
+				rows = append(rows, []string{m.ID, m.Name, strconv.Itoa(m.Source), m.Task.Capability, m.Description, string(properties)})
+			}
+			return rows
+		},
+		table: func() error {
+			model.PrintModelsTable(models)
+			return nil
+		},
+	}.write(format)
+}
+
+// modelDetails is what "midai models show" prints: the catalog entry and the schema
+type modelDetails struct {
+	model.Model
+	Schema model.Schema `json:"schema"`
+}
+
+// runModelsShow implements "midai models show"
+func runModelsShow(args []string) error {
+	var format string
+	var refresh bool
+	fs := newFlagSet("models show", "<name>")
+	fs.BoolVar(&refresh, "refresh", false, "fetch the model catalog even if the cached one is still fresh")
+	addOutputFlag(fs, &format)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+	if format == "csv" {
+		return fmt.Errorf("models show doesn't support the csv output format")
+	}
+
+	// Create the API client, setting up the configuration first if necessary
+	ctx := context.Background()
+	c, err := client.LoadOrSetup(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Find the model by its full or partial name
+	models, err := model.GetAvailableModels(ctx, c, model.ListOptions{Refresh: refresh})
+	if err != nil {
+		return err
+	}
+	name := fs.Arg(0)
+	matches := model.MatchModels(models, name)
+	var details modelDetails
+	switch {
+	case len(matches) == 1:
+		details.Model = matches[0]
+	case len(matches) > 1:
+		names := make([]string, len(matches))
+		for i, m := range matches {
+			names[i] = m.Name
+		}
+		return fmt.Errorf("several models match %q: %s", name, strings.Join(names, ", "))
+	case strings.HasPrefix(name, "@"):
+		details.Model = model.Model{Name: name} // The catalog may not list every model, ask for its schema anyway
+	default:
+		return fmt.Errorf("no model matches %q", name)
+	}
+
+	// Fetch the schema of its input and output
+	if details.Schema, err = model.GetSchema(ctx, c, details.Name); err != nil {
+		return err
+	}
+
+	return output{
+		value: details,
+		table: func() error {
+			return printModelDetails(details)
+		},
+	}.write(format)
+}
+
+// printModelDetails prints a model's catalog entry, its properties and its schema
+func printModelDetails(details modelDetails) error {
+	m := details.Model
+	t := table.New(table.Column{}, table.Column{Wrap: true})
+	t.Append("Name:", m.Name)
+	t.Append("ID:", m.ID)
+	t.Append("Task:", m.Task.Capability)
+	t.Append("Description:", m.Description)
+	if window := m.ContextWindow(); window > 0 {
+		t.Append("Context window:", strconv.Itoa(window)+" tokens")
+	}
+	t.Append("Beta:", strconv.FormatBool(m.Beta()))
+	t.Append("LoRA:", strconv.FormatBool(m.LoRA()))
+	for _, p := range m.Prices() {
+		t.Append("Price:", fmt.Sprintf("%g %s %s", p.Price, p.Currency, p.Unit))
+	}
+	if err := t.Print(); err != nil {
+		return err
+	}
+
+	// List every property, including those without an accessor
+	if len(m.Properties) > 0 {
+		fmt.Println("\nProperties:")
+		props := table.New(table.Column{}, table.Column{Wrap: true})
+		props.Indent = 2
+		for _, p := range m.Properties {
+			props.Append(p.PropertyID, p.String())
+		}
+		if err := props.Print(); err != nil {
+			return err
+		}
+	}
+
+	for _, schema := range []struct {
+		title string
+		value json.RawMessage
+	}{{"Input schema", details.Schema.Input}, {"Output schema", details.Schema.Output}} {
+		fmt.Printf("\n%s:\n", schema.title)
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, schema.value, "  ", "  "); err != nil || len(schema.value) == 0 {
+			fmt.Println("  (not available)")
+			continue
+		}
+		fmt.Printf("  %s\n", indented.String())
+	}
+	return nil
+}
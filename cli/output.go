@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"MidAI/settings"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// output renders a listing in one of the formats of the output_format setting
+type output struct {
+	value any               // Value encoded as JSON or YAML
+	rows  func() [][]string // CSV rows, starting with the header
+	table func() error      // Prints the human readable table
+}
+
+// addOutputFlag defines the -output flag, defaulting to the output_format setting
+func addOutputFlag(fs *flag.FlagSet, format *string) {
+	fs.StringVar(format, "output", settings.String(settings.OutputFormat), "output format: table, json, csv or yaml")
+}
+
+// write prints the listing in format
+func (o output) write(format string) error {
+	s, _ := settings.Find(settings.OutputFormat)
+	if err := s.Validate(format); err != nil {
+		return err
+	}
+
+	switch format {
+	case "json":
+		return writeJSON(o.value)
+	case "yaml":
+		return writeYAML(o.value)
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		if err := w.WriteAll(o.rows()); err != nil {
+			return fmt.Errorf("failed to write CSV: %w", err)
+		}
+		return nil
+	default:
+		return o.table()
+	}
+}
+
+// writeJSON prints v as indented JSON
+func writeJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeYAML prints v as YAML. The value goes through its JSON encoding first,
+// so the YAML uses the same field names and order as the JSON output.
+func writeYAML(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	// JSON is valid YAML; decode it into nodes and switch them to block style
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// blockStyle clears the flow style of JSON objects and arrays as well as the
+// quoting of strings that don't need it, recursively
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
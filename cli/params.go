@@ -0,0 +1,43 @@
+package cli
+
+import (
+	gentext "MidAI/cap/text"
+	"flag"
+	"strings"
+)
+
+// paramUsage describes the generation parameter flags
+var paramUsage = map[string]string{
+	"temperature":        "sampling temperature, 0 to 5",
+	"max_tokens":         "maximum number of tokens to generate",
+	"top_p":              "nucleus sampling probability mass, 0.001 to 1",
+	"top_k":              "number of most likely tokens sampled from, 1 to 50",
+	"seed":               "random seed for reproducible output, 1 to 9999999999",
+	"repetition_penalty": "penalty for repeated tokens, 0 to 2",
+	"frequency_penalty":  "penalty growing with token frequency, -2 to 2",
+	"presence_penalty":   "penalty for tokens already present, -2 to 2",
+}
+
+// paramFlag is a flag.Value setting a single generation parameter.
+// Parameters whose flag isn't given stay unset so the model's defaults apply.
+type paramFlag struct {
+	params *gentext.Params
+	name   string
+}
+
+// String implements flag.Value
+func (f paramFlag) String() string {
+	return ""
+}
+
+// Set implements flag.Value, validating the value's range
+func (f paramFlag) Set(value string) error {
+	return f.params.Set(f.name, value)
+}
+
+// addParamFlags registers a flag such as -max-tokens for every generation parameter
+func addParamFlags(fs *flag.FlagSet, params *gentext.Params) {
+	for _, name := range gentext.ParamNames {
+		fs.Var(paramFlag{params: params, name: name}, strings.ReplaceAll(name, "_", "-"), paramUsage[name])
+	}
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"MidAI/auth"
+	gentext "MidAI/cap/text"
+	"MidAI/table"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// runPersonasList implements "midai personas list"
+func runPersonasList(args []string) error {
+	fs := newFlagSet("personas list", "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	file, err := auth.LoadFile()
+	if err != nil {
+		return err
+	}
+	if len(file.Personas) == 0 {
+		fmt.Println(`No personas defined, add one with "midai personas set".`)
+		return nil
+	}
+
+	// Print the personas sorted by name
+	names := make([]string, 0, len(file.Personas))
+	for name := range file.Personas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	t := table.New(
+		table.Column{Header: "NAME"},
+		table.Column{Header: "MODEL"},
+		table.Column{Header: "PARAMS"},
+		table.Column{Header: "SYSTEM PROMPT", Max: 60},
+	)
+	for _, name := range names {
+		p := file.Personas[name]
+		t.Append(name, p.Model, string(p.Params), p.SystemPrompt)
+	}
+	return t.Print()
+}
+
+// runPersonasSet implements "midai personas set"
+func runPersonasSet(args []string) error {
+	var (
+		persona auth.Persona
+		params  gentext.Params
+	)
+	fs := newFlagSet("personas set", "<name>")
+	fs.StringVar(&persona.SystemPrompt, "system", "", "system prompt of the persona")
+	fs.StringVar(&persona.Model, "model", "", "preferred model of the persona")
+	addParamFlags(fs, &params)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || persona.SystemPrompt == "" {
+		fmt.Fprintln(os.Stderr, "midai personas set: a name and -system are required")
+		fs.Usage()
+		return errUsage
+	}
+	name := fs.Arg(0)
+
+	// Only parameters given on the command line are stored
+	if params != (gentext.Params{}) {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		persona.Params = data
+	}
+
+	file, err := auth.LoadFile()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration, run \"midai config set\" first: %w", err)
+	}
+	if file.Personas == nil {
+		file.Personas = map[string]auth.Persona{}
+	}
+	file.Personas[name] = persona
+	if err := auth.SaveFile(file); err != nil {
+		return err
+	}
+	fmt.Printf("Persona %q saved.\n", name)
+	return nil
+}
+
+// runPersonasRemove implements "midai personas remove"
+func runPersonasRemove(args []string) error {
+	fs := newFlagSet("personas remove", "<name>")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+	name := fs.Arg(0)
+
+	file, err := auth.LoadFile()
+	if err != nil {
+		return err
+	}
+	if _, ok := file.Personas[name]; !ok {
+		return fmt.Errorf("persona %q not found", name)
+	}
+	delete(file.Personas, name)
+	if err := auth.SaveFile(file); err != nil {
+		return err
+	}
+	fmt.Printf("Persona %q removed.\n", name)
+	return nil
+}
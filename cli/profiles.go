@@ -0,0 +1,158 @@
+package cli
+
+import (
+	"MidAI/auth"
+	"MidAI/table"
+	"errors"
+	"fmt"
+)
+
+// runProfilesList implements "midai profiles list"
+func runProfilesList(args []string) error {
+	fs := newFlagSet("profiles list", "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	file, err := auth.LoadFile()
+	if err != nil {
+		return err
+	}
+
+	// Mark the default profile and the one currently selected
+	active := file.ActiveProfileName()
+	t := table.New(
+		table.Column{},
+		table.Column{Header: "NAME"},
+		table.Column{Header: "ACCOUNT ID"},
+		table.Column{Header: "TOKEN"},
+	)
+	for _, name := range file.ProfileNames() {
+		p := file.Profiles[name]
+		marker, label := "", name
+		if name == active {
+			marker = "*"
+		}
+		if name == file.DefaultProfile {
+			label += " (default)"
+		}
+		token := maskToken(p.Token)
+		if p.TokenCommand != "" {
+			token = "token_command: " + p.TokenCommand
+		} else if p.EncryptedToken != nil {
+			token = "(encrypted)"
+		}
+		t.Append(marker, label, p.AccountID, token)
+	}
+	return t.Print()
+}
+
+// runProfilesAdd implements "midai profiles add"
+func runProfilesAdd(args []string) error {
+	var (
+		profile auth.Profile
+		force   bool
+	)
+	fs := newFlagSet("profiles add", "<name>")
+	fs.StringVar(&profile.AccountID, "account-id", "", "Cloudflare Account ID")
+	fs.StringVar(&profile.Token, "token", "", "Cloudflare API Token with Workers AI Read and Edit access")
+	fs.StringVar(&profile.TokenCommand, "token-command", "", `command printing the API Token, e.g. "pass show cloudflare"`)
+	fs.BoolVar(&force, "force", false, "save the credentials even if they can't be verified")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+	name := fs.Arg(0)
+
+	file, err := auth.LoadFile()
+	if err != nil && !errors.Is(err, auth.ErrConfigNotExist) {
+		return err
+	}
+	if _, exists := file.Profiles[name]; exists {
+		return fmt.Errorf("profile %q already exists, use \"midai --profile %s config set\" to change it", name, name)
+	}
+
+	// Prompt for the credentials that weren't given as flags
+	if profile.AccountID == "" || !profile.HasToken() {
+		profile = auth.PromptForProfile(profile)
+	}
+	if profile.AccountID == "" || !profile.HasToken() {
+		return fmt.Errorf("account ID and token cannot be empty")
+	}
+
+	// Refuse to save credentials that don't work
+	if err := verifyProfile(name, profile, force); err != nil {
+		return err
+	}
+
+	file.SetProfile(name, profile)
+	if err := auth.SaveFile(file); err != nil {
+		return err
+	}
+	fmt.Printf("Profile %q added.\n", name)
+	return nil
+}
+
+// runProfilesRename implements "midai profiles rename"
+func runProfilesRename(args []string) error {
+	fs := newFlagSet("profiles rename", "<old-name> <new-name>")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errUsage
+	}
+	return updateProfiles(func(file *auth.File) error {
+		return file.RenameProfile(fs.Arg(0), fs.Arg(1))
+	}, fmt.Sprintf("Profile %q renamed to %q.", fs.Arg(0), fs.Arg(1)))
+}
+
+// runProfilesRemove implements "midai profiles remove"
+func runProfilesRemove(args []string) error {
+	fs := newFlagSet("profiles remove", "<name>")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+	return updateProfiles(func(file *auth.File) error {
+		return file.RemoveProfile(fs.Arg(0))
+	}, fmt.Sprintf("Profile %q removed.", fs.Arg(0)))
+}
+
+// runProfilesUse implements "midai profiles use"
+func runProfilesUse(args []string) error {
+	fs := newFlagSet("profiles use", "<name>")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+	return updateProfiles(func(file *auth.File) error {
+		return file.UseProfile(fs.Arg(0))
+	}, fmt.Sprintf("Profile %q is now the default.", fs.Arg(0)))
+}
+
+// updateProfiles applies change to the config file, saves it and prints done
+func updateProfiles(change func(file *auth.File) error, done string) error {
+	file, err := auth.LoadFile()
+	if err != nil {
+		return err
+	}
+	if err := change(&file); err != nil {
+		return err
+	}
+	if err := auth.SaveFile(file); err != nil {
+		return err
+	}
+	fmt.Println(done)
+	return nil
+}
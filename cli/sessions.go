@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"MidAI/session"
+	"MidAI/table"
+	"fmt"
+	"path"
+	"strconv"
+	"time"
+)
+
+// runSessionsList implements "midai sessions list"
+func runSessionsList(args []string) error {
+	var format string
+	fs := newFlagSet("sessions list", "")
+	addOutputFlag(fs, &format)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	sessions, err := session.List()
+	if err != nil {
+		return err
+	}
+	if sessions == nil {
+		sessions = []session.Session{} // Print an empty array rather than null
+	}
+	return output{
+		value: sessions,
+		rows: func() [][]string {
+			rows := [][]string{{"id", "model", "messages", "created_at", "updated_at", "title"}}
+			for _, s := range sessions {
+				rows = append(rows, []string{s.ID, s.Model, strconv.Itoa(len(s.Messages)),
+					s.CreatedAt.Format(time.RFC3339), s.UpdatedAt.Format(time.RFC3339), s.Title()})
+			}
+			return rows
+		},
+		table: func() error {
+			if len(sessions) == 0 {
+				fmt.Println("No saved sessions.")
+				return nil
+			}
+
+			// Print one aligned row per session
+			t := table.New(
+				table.Column{Header: "ID"},
+				table.Column{Header: "MODEL"},
+				table.Column{Header: "MESSAGES", AlignRight: true},
+				table.Column{Header: "UPDATED"},
+				table.Column{Header: "TITLE", Max: 50},
+			)
+			for _, s := range sessions {
+				t.Append(s.ID, path.Base(s.Model), strconv.Itoa(len(s.Messages)), s.UpdatedAt.Format("2006-01-02 15:04"), s.Title())
+			}
+			return t.Print()
+		},
+	}.write(format)
+}
+
+// runSessionsDelete implements "midai sessions delete"
+func runSessionsDelete(args []string) error {
+	fs := newFlagSet("sessions delete", "<id>...")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	for _, id := range fs.Args() {
+		if err := session.Delete(id); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted session %s\n", id)
+	}
+	return nil
+}
+
+// runSessionsPrune implements "midai sessions prune"
+func runSessionsPrune(args []string) error {
+	var olderThan time.Duration
+	fs := newFlagSet("sessions prune", "")
+	fs.DurationVar(&olderThan, "older-than", 30*24*time.Hour, "delete sessions not updated for this long, e.g. 72h")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	removed, err := session.Prune(olderThan)
+	for _, id := range removed {
+		fmt.Printf("Deleted session %s\n", id)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d session(s) deleted.\n", len(removed))
+	return nil
+}
@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"MidAI/settings"
+	"MidAI/table"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// runConfigGet implements "midai config get"
+func runConfigGet(args []string) error {
+	fs := newFlagSet("config get", "<key>")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+
+	s, err := settings.Find(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	fmt.Println(settings.String(s.Key))
+	return nil
+}
+
+// setSetting implements "midai config set <key> <value>", saving a setting to the settings file
+func setSetting(key, value string) error {
+	s, err := settings.Find(key)
+	if err != nil {
+		return err
+	}
+	if err := s.Validate(value); err != nil {
+		return err
+	}
+
+	values, err := settings.LoadFile()
+	if err != nil {
+		return err
+	}
+	values[s.Key] = value
+	if err := settings.SaveFile(values); err != nil {
+		return err
+	}
+	fmt.Printf("%s saved to %s\n", s.Key, settings.File)
+
+	// Point out that the new value doesn't take effect while the environment overrides it
+	if os.Getenv(s.Env) != "" {
+		fmt.Printf("Note: the %s environment variable overrides this setting.\n", s.Env)
+	}
+	return nil
+}
+
+// runConfigUnset implements "midai config unset"
+func runConfigUnset(args []string) error {
+	fs := newFlagSet("config unset", "<key>")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errUsage
+	}
+
+	// Unknown keys can be removed as well, e.g. typos in a hand-edited file
+	values, err := settings.LoadFile()
+	if err != nil {
+		return err
+	}
+	key := fs.Arg(0)
+	if s, err := settings.Find(key); err == nil {
+		key = s.Key
+	}
+	if _, ok := values[key]; !ok {
+		return fmt.Errorf("%s is not set in %s", key, settings.File)
+	}
+	delete(values, key)
+	if err := settings.SaveFile(values); err != nil {
+		return err
+	}
+	fmt.Printf("%s removed from %s\n", key, settings.File)
+	return nil
+}
+
+// runConfigList implements "midai config list"
+func runConfigList(args []string) error {
+	fs := newFlagSet("config list", "")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	// Print every setting with its effective value and where the value comes from
+	t := table.New(
+		table.Column{Header: "KEY"},
+		table.Column{Header: "VALUE", Max: 40},
+		table.Column{Header: "SOURCE"},
+		table.Column{Header: "DESCRIPTION", Wrap: true},
+	)
+	for _, s := range settings.Settings {
+		value, source := settings.Lookup(s.Key)
+		switch source {
+		case settings.SourceEnv:
+			source = s.Env
+		case settings.SourceFile:
+			source = "settings file"
+		}
+		t.Append(s.Key, value, source, s.Help)
+	}
+	if err := t.Print(); err != nil {
+		return err
+	}
+
+	// Report keys the settings file holds but nothing reads
+	values, err := settings.LoadFile()
+	if err != nil {
+		return err
+	}
+	if unknown := settings.UnknownKeys(values); len(unknown) > 0 {
+		fmt.Fprintf(os.Stderr, "\nWarning: unknown settings in %s: %s\n", settings.File, strings.Join(unknown, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,321 @@
+package client
+
+import (
+	"MidAI/auth"
+	"MidAI/settings"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// DefaultBaseURL is the default of the api_url setting
+const DefaultBaseURL = settings.DefaultAPIURL
+
+// Client talks to the Workers AI endpoints of the Cloudflare API.
+// BaseURL and HTTPClient can be replaced, e.g. to point the client at a local stand-in server.
+type Client struct {
+	BaseURL    string       // API base URL without a trailing slash
+	HTTPClient *http.Client // HTTP client used to execute requests
+	Config     auth.Config  // Account ID and API token used to authenticate requests
+	Validate   bool         // Check model inputs against the model's input schema before sending them
+
+	MaxAttempts int           // Attempts of a request failing transiently, including the first; 0 or 1 disables retries
+	MaxElapsed  time.Duration // Time after which a failed request isn't retried anymore, 0 for no limit
+
+	schemasMu sync.Mutex                    // Guards schemas
+	schemas   map[string]*jsonschema.Schema // Compiled input schemas by model, nil if unavailable
+}
+
+// Response is the envelope every Cloudflare API response is wrapped in
+type Response struct {
+	Success bool            `json:"success"` // Whether the request succeeded
+	Errors  []APIError      `json:"errors"`  // Errors reported when the request failed
+	Result  json.RawMessage `json:"result"`  // Endpoint specific payload
+
+	ResultInfo ResultInfo `json:"result_info"` // Pagination details of list endpoints
+}
+
+// ResultInfo describes the page of a paginated result
+type ResultInfo struct {
+	Page       int `json:"page"`        // Number of the page, starting at 1
+	PerPage    int `json:"per_page"`    // Maximum number of results per page
+	Count      int `json:"count"`       // Number of results on this page
+	TotalCount int `json:"total_count"` // Number of results on all pages
+}
+
+// New creates a Client for the given configuration using the api_url, connect_timeout,
+// response_timeout, retry_max_attempts, retry_max_elapsed and validate_requests settings
+func New(config auth.Config) *Client {
+	return &Client{
+		BaseURL:     strings.TrimRight(settings.String(settings.APIURL), "/"),
+		HTTPClient:  &http.Client{Transport: newTransport()},
+		Config:      config,
+		Validate:    !SkipValidation && settings.String(settings.ValidateRequests) == "true",
+		MaxAttempts: settings.Int(settings.RetryMaxAttempts),
+		MaxElapsed:  settings.Duration(settings.RetryMaxElapsed),
+	}
+}
+
+// newTransport creates the HTTP transport limiting the time to connect and the time
+// until the response headers arrive. The body of a streamed response isn't limited,
+// as a long answer may take a while to generate.
+func newTransport() *http.Transport {
+	connectTimeout := settings.Duration(settings.ConnectTimeout)
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: connectTimeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = connectTimeout
+	transport.ResponseHeaderTimeout = settings.Duration(settings.ResponseTimeout)
+	return transport
+}
+
+// accountURL builds the URL of an endpoint scoped to the configured account
+func (c *Client) accountURL(endpoint string) string {
+	return fmt.Sprintf("%s/accounts/%s/%s", strings.TrimRight(c.BaseURL, "/"), c.Config.AccountID, strings.TrimLeft(endpoint, "/"))
+}
+
+// newRequest creates an authenticated request, encoding input as the JSON body if it is not nil
+func (c *Client) newRequest(ctx context.Context, method, url string, input any) (*http.Request, error) {
+	var body io.Reader
+	if input != nil {
+		// Marshal the input to JSON
+		data, err := json.Marshal(input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	// Create a new request
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Set the authorization header using the API token from the config
+	if input != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.Config.Token))
+	return req, nil
+}
+
+// do executes a request and returns the response if its status is OK (200).
+// Any other status is returned as an *APIError. Rate limits, server errors and
+// failures to reach the API are retried with backoff where that's safe, see retryable,
+// up to MaxAttempts attempts and MaxElapsed time; a stream is only retried until its
+// response starts.
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		res, err := send(httpClient, req)
+		if err == nil || attempt >= c.MaxAttempts || !retryable(req, err) {
+			return res, err
+		}
+
+		// Give up if waiting would exceed the time allowed for the request
+		delay := retryDelay(attempt, err)
+		if c.MaxElapsed > 0 && time.Since(start)+delay > c.MaxElapsed {
+			return nil, err
+		}
+		slog.Debug("retrying request", "method", req.Method, "url", req.URL.Redacted(),
+			"attempt", attempt+1, "max_attempts", c.MaxAttempts, "delay", delay, "error", err)
+		if err := sleep(req.Context(), delay); err != nil {
+			return nil, fmt.Errorf("failed to execute request: %w", err)
+		}
+
+		// The body was consumed by the failed attempt, send a fresh copy of it
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("failed to create request: %w", err)
+			}
+			req.Body = body
+		}
+	}
+}
+
+// send executes a single attempt of a request, see do
+func send(httpClient *http.Client, req *http.Request) (*http.Response, error) {
+	// Execute the request
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute request: %w", err)
+	}
+
+	// A conditional request found the resource unchanged
+	if res.StatusCode == http.StatusNotModified {
+		res.Body.Close()
+		return nil, ErrNotModified
+	}
+
+	// Check if the response status is OK (200)
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		respBody, _ := io.ReadAll(res.Body)
+		return nil, newAPIError(res, respBody)
+	}
+	return res, nil
+}
+
+// call executes a request and decodes the result of the response envelope into out.
+// Failures reported by the API are returned as an *APIError.
+func (c *Client) call(req *http.Request, out any) error {
+	res, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, err = decode(res, out)
+	return err
+}
+
+// decode reads the response envelope of res and decodes its result into out,
+// skipping the result if out is nil. The envelope is returned for its pagination details.
+func decode(res *http.Response, out any) (Response, error) {
+	// Read the response body
+	respBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	// Unmarshal the response envelope
+	var envelope Response
+	if err := json.Unmarshal(respBody, &envelope); err != nil {
+		return Response{}, fmt.Errorf("failed to parse JSON response: %w\nRaw Response: %s", err, string(respBody))
+	}
+
+	// Check if the API response indicates success
+	if !envelope.Success {
+		return envelope, newAPIError(res, respBody)
+	}
+
+	// Decode the result into the caller's value
+	if out == nil {
+		return envelope, nil
+	}
+	if err := json.Unmarshal(envelope.Result, out); err != nil {
+		return envelope, fmt.Errorf("failed to decode result: %w", err)
+	}
+	return envelope, nil
+}
+
+// Run executes a model with the given input and decodes the result into out
+func (c *Client) Run(ctx context.Context, model string, input any, out any) error {
+	if err := c.validateInput(ctx, model, input); err != nil {
+		return err
+	}
+	return c.run(ctx, model, input, out)
+}
+
+// run executes a model like Run, without validating the input
+func (c *Client) run(ctx context.Context, model string, input any, out any) error {
+	req, err := c.newRequest(ctx, http.MethodPost, c.accountURL("ai/run/"+model), input)
+	if err != nil {
+		return err
+	}
+	return c.call(req, out)
+}
+
+// Stream executes a model with the given input and passes the payload of every
+// server-sent event "data:" frame to onData until the "[DONE]" terminator arrives.
+// If the stream ends without the terminator, an error wrapping io.ErrUnexpectedEOF is returned.
+// The input is expected to request streaming, e.g. by setting "stream": true.
+func (c *Client) Stream(ctx context.Context, model string, input any, onData func(data []byte) error) error {
+	if err := c.validateInput(ctx, model, input); err != nil {
+		return err
+	}
+	req, err := c.newRequest(ctx, http.MethodPost, c.accountURL("ai/run/"+model), input)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	res, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// Read the stream line by line, allowing for frames larger than the default buffer
+	scanner := bufio.NewScanner(res.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		// Only "data:" fields carry payloads; blank lines, comments and other fields are skipped
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+
+		// The stream is terminated by a "[DONE]" frame
+		if data == "[DONE]" {
+			return nil
+		}
+		if err := onData([]byte(data)); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	// The body ended without the terminator, e.g. because the connection dropped,
+	// so the response is incomplete
+	return fmt.Errorf("stream ended before the [DONE] frame: %w", io.ErrUnexpectedEOF)
+}
+
+// ModelSchema fetches the JSON schemas of a model's input and output and decodes them into out
+func (c *Client) ModelSchema(ctx context.Context, model string, out any) error {
+	query := url.Values{"model": {model}}
+	req, err := c.newRequest(ctx, http.MethodGet, c.accountURL("ai/models/schema")+"?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	return c.call(req, out)
+}
+
+// ListModels fetches a page of the Workers AI model catalog matching query, such as
+// {"task": ["Text Generation"], "page": ["2"]}, and decodes it into out. It returns the
+// page's pagination details and its ETag, if the API reports one. If etag is set, the
+// page is only sent if it changed since; ErrNotModified is returned otherwise.
+func (c *Client) ListModels(ctx context.Context, query url.Values, etag string, out any) (ResultInfo, string, error) {
+	endpoint := c.accountURL("ai/models/search")
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := c.newRequest(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return ResultInfo{}, "", err
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	res, err := c.do(req)
+	if err != nil {
+		return ResultInfo{}, "", err
+	}
+	defer res.Body.Close()
+	envelope, err := decode(res, out)
+	if err != nil {
+		return ResultInfo{}, "", err
+	}
+	return envelope.ResultInfo, res.Header.Get("ETag"), nil
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newTestClient creates a Client talking to a test server running handler
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return &Client{BaseURL: srv.URL, HTTPClient: srv.Client()}
+}
+
+// sseHandler answers every request with the given server-sent event frames
+func sseHandler(frames ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, frame := range frames {
+			fmt.Fprintf(w, "data: %s\n\n", frame)
+		}
+	}
+}
+
+// collect streams a request, returning the payloads of the frames received
+func collect(c *Client) ([]string, error) {
+	var payloads []string
+	err := c.Stream(context.Background(), "@cf/test/model", map[string]any{"stream": true}, func(data []byte) error {
+		payloads = append(payloads, string(data))
+		return nil
+	})
+	return payloads, err
+}
+
+func TestStreamDone(t *testing.T) {
+	c := newTestClient(t, sseHandler(`{"response":"Hel"}`, `{"response":"lo"}`, "[DONE]", `{"response":"ignored"}`))
+
+	payloads, err := collect(c)
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	want := []string{`{"response":"Hel"}`, `{"response":"lo"}`}
+	if strings.Join(payloads, "|") != strings.Join(want, "|") {
+		t.Errorf("Stream() payloads = %q, want %q", payloads, want)
+	}
+}
+
+func TestStreamTruncated(t *testing.T) {
+	c := newTestClient(t, sseHandler(`{"response":"Hel"}`, `{"response":"lo"}`))
+
+	payloads, err := collect(c)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("Stream() error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if len(payloads) != 2 {
+		t.Errorf("Stream() passed %d payloads, want 2", len(payloads))
+	}
+}
+
+func TestStreamAPIError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cf-Ray", "abc123")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"success":false,"errors":[{"code":5006,"message":"Bad input"}],"result":null}`)
+	})
+
+	payloads, err := collect(c)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Stream() error = %v, want an *APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest || apiErr.Code != 5006 || apiErr.Message != "Bad input" || apiErr.RayID != "abc123" {
+		t.Errorf("Stream() error = %+v", apiErr)
+	}
+	if len(payloads) != 0 {
+		t.Errorf("Stream() passed %d payloads, want none", len(payloads))
+	}
+}
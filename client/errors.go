@@ -0,0 +1,72 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrNotModified is returned for a conditional request whose resource didn't change
+var ErrNotModified = errors.New("not modified")
+
+// APIError is an error reported by the Cloudflare API, decoded from the
+// {"success":false,"errors":[{"code":...,"message":...}]} response envelope
+type APIError struct {
+	Code       int    `json:"code"`    // Cloudflare error code, 0 if the response carried none
+	Message    string `json:"message"` // Human readable error message
+	StatusCode int    `json:"-"`       // HTTP status code of the response
+	RayID      string `json:"-"`       // Cloudflare Ray ID identifying the request, useful for support tickets
+
+	RetryAfter time.Duration `json:"-"` // Wait the API asked for before retrying, 0 if it didn't say
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	var b strings.Builder
+	b.WriteString("cloudflare API error")
+	if e.Code != 0 {
+		fmt.Fprintf(&b, " %d", e.Code)
+	}
+	if e.StatusCode != 0 {
+		fmt.Fprintf(&b, " (HTTP %d %s)", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	if e.Message != "" {
+		fmt.Fprintf(&b, ": %s", e.Message)
+	}
+	if e.RayID != "" {
+		fmt.Fprintf(&b, " [ray ID %s]", e.RayID)
+	}
+	return b.String()
+}
+
+// newAPIError builds an APIError from a failed response and its already read body.
+// When the body is not a Cloudflare envelope the raw body is used as the message.
+func newAPIError(res *http.Response, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: res.StatusCode,
+		RayID:      res.Header.Get("Cf-Ray"),
+		RetryAfter: parseRetryAfter(res.Header.Get("Retry-After"), time.Now()),
+	}
+
+	// Try to decode the errors of the response envelope
+	var envelope Response
+	if err := json.Unmarshal(body, &envelope); err == nil && len(envelope.Errors) > 0 {
+		apiErr.Code = envelope.Errors[0].Code
+		messages := make([]string, 0, len(envelope.Errors))
+		for _, e := range envelope.Errors {
+			messages = append(messages, e.Message)
+		}
+		apiErr.Message = strings.Join(messages, "; ")
+		return apiErr
+	}
+
+	// Fall back to the raw body, or the status text if the body is empty
+	apiErr.Message = strings.TrimSpace(string(body))
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(res.StatusCode)
+	}
+	return apiErr
+}
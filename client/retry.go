@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// retryBaseDelay is the wait before the first retry, doubled for every further one
+const retryBaseDelay = 500 * time.Millisecond
+
+// retryMaxDelay caps the backoff between two attempts; a longer Retry-After is still honored
+const retryMaxDelay = 30 * time.Second
+
+// retryable reports whether a request that failed with err may succeed when sent
+// again. Idempotent requests are retried after rate limits, server errors and
+// failures to reach the API. Any other request, such as a generation, is only
+// retried if the API certainly didn't act on it: after a rate limit (429) or an
+// unavailable service (503), or if the connection couldn't even be made. A
+// generation that reached the API must not be run (and billed) again.
+// Nothing is retried once the request's context is done.
+func retryable(req *http.Request, err error) bool {
+	if req.Context().Err() != nil || errors.Is(err, ErrNotModified) {
+		return false
+	}
+	idempotent := req.Method == http.MethodGet || req.Method == http.MethodHead || req.Method == http.MethodOptions
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+			return true
+		case http.StatusRequestTimeout, http.StatusInternalServerError, http.StatusBadGateway, http.StatusGatewayTimeout:
+			return idempotent
+		}
+		return false
+	}
+
+	// Failures that sending again won't fix, such as an untrusted certificate or an unknown host
+	var certErr *tls.CertificateVerificationError
+	var dnsErr *net.DNSError
+	if errors.As(err, &certErr) || (errors.As(err, &dnsErr) && dnsErr.IsNotFound) {
+		return false
+	}
+	if idempotent {
+		return true
+	}
+
+	// Failing to connect means the request wasn't sent yet
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// retryDelay returns how long to wait before the next attempt after attempt failed
+// with err. The API's Retry-After wins; otherwise the delay grows exponentially
+// with jitter, so clients that failed together don't retry together.
+func retryDelay(attempt int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter
+	}
+
+	backoff := retryMaxDelay
+	if attempt <= 16 { // Beyond that the shift overflows, and the cap applies anyway
+		backoff = min(retryBaseDelay<<(attempt-1), retryMaxDelay)
+	}
+	return backoff/2 + rand.N(backoff/2+1)
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which is either a
+// number of seconds or an HTTP date. It returns 0 if the value is missing or invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0)
+	}
+	return 0
+}
+
+// sleep waits for d or until ctx is done, returning the context's error in that case
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,226 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingHandler answers with the given statuses in turn, then with success,
+// counting the requests it receives
+func countingHandler(count *atomic.Int32, header http.Header, statuses ...int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		n := int(count.Add(1))
+		if n <= len(statuses) {
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		fmt.Fprint(w, `{"success":true,"result":{}}`)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"5", 5 * time.Second},
+		{" 120 ", 2 * time.Minute},
+		{"-3", 0},
+		{now.Add(10 * time.Second).Format(http.TimeFormat), 10 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"soon", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	// The API's Retry-After wins over the backoff
+	if got := retryDelay(1, &APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: 7 * time.Second}); got != 7*time.Second {
+		t.Errorf("retryDelay() with Retry-After = %v, want 7s", got)
+	}
+
+	// The backoff doubles per attempt up to the cap, with jitter in its upper half
+	for attempt := 1; attempt <= 70; attempt++ {
+		backoff := retryMaxDelay
+		if attempt <= 6 {
+			backoff = min(retryBaseDelay<<(attempt-1), retryMaxDelay)
+		}
+		if got := retryDelay(attempt, errors.New("failed")); got < backoff/2 || got > backoff {
+			t.Errorf("retryDelay(%d) = %v, want between %v and %v", attempt, got, backoff/2, backoff)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	get, _ := http.NewRequest(http.MethodGet, "https://example.com", nil)
+	post, _ := http.NewRequest(http.MethodPost, "https://example.com", nil)
+	dialErr := &url.Error{Op: "Post", URL: "https://example.com", Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}}
+	readErr := &url.Error{Op: "Post", URL: "https://example.com", Err: &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}}
+	timeoutErr := &url.Error{Op: "Post", URL: "https://example.com", Err: errors.New("net/http: timeout awaiting response headers")}
+	dnsErr := &url.Error{Op: "Get", URL: "https://example.com", Err: &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "example.com", IsNotFound: true}}}
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		err  error
+		want bool
+	}{
+		{"429 GET", get, &APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{"429 POST", post, &APIError{StatusCode: http.StatusTooManyRequests}, true},
+		{"503 POST", post, &APIError{StatusCode: http.StatusServiceUnavailable}, true},
+		{"502 GET", get, &APIError{StatusCode: http.StatusBadGateway}, true},
+		{"502 POST", post, &APIError{StatusCode: http.StatusBadGateway}, false},
+		{"500 POST", post, &APIError{StatusCode: http.StatusInternalServerError}, false},
+		{"400 GET", get, &APIError{StatusCode: http.StatusBadRequest}, false},
+		{"not modified", get, ErrNotModified, false},
+		{"dial error POST", post, dialErr, true},
+		{"reset after sending POST", post, readErr, false},
+		{"timeout after sending POST", post, timeoutErr, false},
+		{"timeout after sending GET", get, timeoutErr, true},
+		{"unknown host GET", get, dnsErr, false},
+	}
+	for _, tt := range tests {
+		if got := retryable(tt.req, tt.err); got != tt.want {
+			t.Errorf("retryable(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRetryAfterRateLimit(t *testing.T) {
+	var count atomic.Int32
+	c := newTestClient(t, countingHandler(&count, http.Header{"Retry-After": {"1"}}, http.StatusTooManyRequests))
+	c.MaxAttempts, c.MaxElapsed = 3, time.Minute
+
+	start := time.Now()
+	if err := c.Run(context.Background(), "@cf/test/model", map[string]any{"prompt": "hi"}, nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if count.Load() != 2 {
+		t.Errorf("server received %d requests, want 2", count.Load())
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Run() retried after %v, want the 1s of Retry-After", elapsed)
+	}
+}
+
+func TestRetryBadGatewayOnlyForGet(t *testing.T) {
+	var count atomic.Int32
+	c := newTestClient(t, countingHandler(&count, nil, http.StatusBadGateway, http.StatusBadGateway))
+	c.MaxAttempts, c.MaxElapsed = 2, time.Minute
+
+	// A generation may already have run, it isn't sent again
+	var apiErr *APIError
+	if err := c.Run(context.Background(), "@cf/test/model", map[string]any{"prompt": "hi"}, nil); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+		t.Fatalf("Run() error = %v, want HTTP 502", err)
+	}
+	if count.Load() != 1 {
+		t.Errorf("POST was sent %d times, want 1", count.Load())
+	}
+
+	// Listing the models is safe to repeat
+	if _, _, err := c.ListModels(context.Background(), nil, "", nil); err != nil {
+		t.Fatalf("ListModels() error = %v", err)
+	}
+	if count.Load() != 3 {
+		t.Errorf("server received %d requests, want 3", count.Load())
+	}
+}
+
+func TestRetryTimeoutAfterSending(t *testing.T) {
+	var count atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		count.Add(1)
+		time.Sleep(200 * time.Millisecond)
+	})
+	c.HTTPClient.Transport.(*http.Transport).ResponseHeaderTimeout = 50 * time.Millisecond
+	c.MaxAttempts, c.MaxElapsed = 3, time.Minute
+
+	if err := c.Run(context.Background(), "@cf/test/model", map[string]any{"prompt": "hi"}, nil); err == nil {
+		t.Fatal("Run() succeeded, want a timeout")
+	}
+	if count.Load() != 1 {
+		t.Errorf("POST was sent %d times, want 1", count.Load())
+	}
+}
+
+func TestRetryDialError(t *testing.T) {
+	// Reserve a port and close it again, so connecting to it fails
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	var count atomic.Int32
+	c := &Client{BaseURL: "http://" + addr, HTTPClient: &http.Client{}, MaxAttempts: 2, MaxElapsed: time.Minute}
+	c.HTTPClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		count.Add(1)
+		return http.DefaultTransport.RoundTrip(req)
+	})
+
+	err = c.Run(context.Background(), "@cf/test/model", map[string]any{"prompt": "hi"}, nil)
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) || opErr.Op != "dial" {
+		t.Fatalf("Run() error = %v, want a dial error", err)
+	}
+	if count.Load() != 2 {
+		t.Errorf("POST was attempted %d times, want 2", count.Load())
+	}
+}
+
+func TestRetryMaxElapsed(t *testing.T) {
+	var count atomic.Int32
+	c := newTestClient(t, countingHandler(&count, http.Header{"Retry-After": {"10"}}, http.StatusTooManyRequests))
+	c.MaxAttempts, c.MaxElapsed = 5, time.Second
+
+	// Waiting 10s would exceed the 1s allowed, so the rate limit is reported right away
+	start := time.Now()
+	var apiErr *APIError
+	if err := c.Run(context.Background(), "@cf/test/model", map[string]any{"prompt": "hi"}, nil); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Run() error = %v, want HTTP 429", err)
+	}
+	if count.Load() != 1 || time.Since(start) > time.Second {
+		t.Errorf("Run() sent %d requests in %v, want 1 without waiting", count.Load(), time.Since(start))
+	}
+}
+
+func TestRetryCancelledWhileWaiting(t *testing.T) {
+	var count atomic.Int32
+	c := newTestClient(t, countingHandler(&count, http.Header{"Retry-After": {"10"}}, http.StatusServiceUnavailable))
+	c.MaxAttempts, c.MaxElapsed = 3, time.Minute
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	if err := c.Run(ctx, "@cf/test/model", map[string]any{"prompt": "hi"}, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() error = %v, want context.Canceled", err)
+	}
+	if count.Load() != 1 || time.Since(start) > 5*time.Second {
+		t.Errorf("Run() sent %d requests in %v, want 1 and to stop waiting", count.Load(), time.Since(start))
+	}
+}
+
+// roundTripFunc turns a function into an http.RoundTripper
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
@@ -0,0 +1,160 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// SkipValidation disables checking requests against the model's input schema,
+// e.g. when the schema is wrong or a new field isn't documented yet. It is set
+// by the global --no-validate flag and applies to clients created afterwards.
+var SkipValidation bool
+
+// ValidationError reports the fields of a request that don't match the model's input schema
+type ValidationError struct {
+	Model  string       // Name of the model the request was meant for
+	Fields []FieldError // Problems found, one per field
+}
+
+// FieldError is a problem with a single field of a request
+type FieldError struct {
+	Field   string // JSON pointer of the field such as "/messages/0/role", empty for the request itself
+	Message string // What is wrong with the field
+}
+
+// Error implements the error interface
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid input for %s (run \"midai --no-validate <command>\" to send it anyway):", e.Model)
+	for _, f := range e.Fields {
+		field := strings.TrimPrefix(f.Field, "/")
+		if field == "" {
+			field = "request"
+		}
+		fmt.Fprintf(&b, "\n  %s: %s", field, f.Message)
+	}
+	return b.String()
+}
+
+// validateInput checks input against the input schema of model before it is sent.
+// Validation is skipped if the schema can't be fetched or compiled, as the API
+// checks the request anyway; only definite mismatches are reported.
+func (c *Client) validateInput(ctx context.Context, model string, input any) error {
+	if !c.Validate || input == nil {
+		return nil
+	}
+	schema := c.inputSchema(ctx, model)
+	if schema == nil {
+		return nil
+	}
+
+	// Validate the request as it is sent, i.e. its JSON encoding
+	data, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	var value any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber() // Keep numbers exact for range checks
+	if err := dec.Decode(&value); err != nil {
+		return fmt.Errorf("failed to decode request: %w", err)
+	}
+
+	err = schema.Validate(value)
+	if ve, ok := err.(*jsonschema.ValidationError); ok {
+		validationErr := &ValidationError{Model: model}
+		for _, leaf := range leafErrors(ve) {
+			validationErr.Fields = append(validationErr.Fields, FieldError{Field: leaf.InstanceLocation, Message: leaf.Message})
+		}
+		return validationErr
+	}
+	return nil // Other errors are problems of the schema, not of the request
+}
+
+// inputSchema returns the compiled input schema of model, or nil if it isn't available.
+// Schemas are fetched once per client, as is the answer that a model has none, so a
+// missing schema doesn't cost a request per message. Other failures to fetch one,
+// e.g. a cancelled request or a server error, are tried again with the next request.
+func (c *Client) inputSchema(ctx context.Context, model string) *jsonschema.Schema {
+	c.schemasMu.Lock()
+	defer c.schemasMu.Unlock()
+	if schema, ok := c.schemas[model]; ok {
+		return schema
+	}
+
+	var raw struct {
+		Input json.RawMessage `json:"input"`
+	}
+	var schema *jsonschema.Schema
+	err := c.ModelSchema(ctx, model, &raw)
+	var apiErr *APIError
+	switch {
+	case err == nil && len(raw.Input) > 0:
+		schema = compileSchema(model, raw.Input)
+	case err == nil, errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
+		// The model has no schema to check against
+	default:
+		if ctx.Err() == nil {
+			fmt.Fprintf(os.Stderr, "Warning: sending the input for %s unchecked, its schema couldn't be fetched: %v\n", model, err)
+		}
+		return nil
+	}
+
+	if c.schemas == nil {
+		c.schemas = make(map[string]*jsonschema.Schema)
+	}
+	c.schemas[model] = schema
+	return schema
+}
+
+// compileSchema compiles a model's input schema, returning nil if it is invalid.
+// References to other documents aren't followed, the schemas are self-contained.
+func compileSchema(model string, data []byte) *jsonschema.Schema {
+	compiler := jsonschema.NewCompiler()
+	compiler.LoadURL = func(url string) (io.ReadCloser, error) {
+		return nil, fmt.Errorf("not loading %s", url)
+	}
+	url := "schema:///" + model
+	if err := compiler.AddResource(url, bytes.NewReader(data)); err != nil {
+		return nil
+	}
+	schema, err := compiler.Compile(url)
+	if err != nil {
+		return nil
+	}
+	return schema
+}
+
+// leafErrors returns the innermost errors of ve, which name the offending fields.
+// Of the alternatives of "oneOf" and "anyOf", only the closest match is reported:
+// a chat request with a bad temperature shouldn't also be told it lacks a prompt.
+func leafErrors(ve *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(ve.Causes) == 0 {
+		return []*jsonschema.ValidationError{ve}
+	}
+
+	if strings.HasSuffix(ve.KeywordLocation, "/oneOf") || strings.HasSuffix(ve.KeywordLocation, "/anyOf") {
+		var best []*jsonschema.ValidationError
+		for _, cause := range ve.Causes {
+			if leaves := leafErrors(cause); best == nil || len(leaves) < len(best) {
+				best = leaves
+			}
+		}
+		return best
+	}
+
+	var leaves []*jsonschema.ValidationError
+	for _, cause := range ve.Causes {
+		leaves = append(leaves, leafErrors(cause)...)
+	}
+	return leaves
+}
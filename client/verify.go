@@ -0,0 +1,125 @@
+package client
+
+import (
+	"MidAI/auth"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// VerifyModel is the small embedding model run to check that a token may use Workers AI
+const VerifyModel = "@cf/baai/bge-small-en-v1.5"
+
+// TokenStatus is the result of Cloudflare's token verify endpoint
+type TokenStatus struct {
+	ID        string `json:"id"`         // Token ID
+	Status    string `json:"status"`     // "active", "disabled" or "expired"
+	ExpiresOn string `json:"expires_on"` // Expiry date, empty if the token never expires
+	NotBefore string `json:"not_before"` // Date the token becomes valid, empty if it's valid right away
+}
+
+// Verification reports whether credentials can be used with Workers AI
+type Verification struct {
+	Token    TokenStatus // Status reported by the token verify endpoint
+	TokenErr error       // Error verifying the token, nil if it was verified
+	ReadErr  error       // Error listing the Workers AI models, nil with read access
+	EditErr  error       // Error running a Workers AI model, nil with edit access
+}
+
+// VerifyToken asks Cloudflare about the status of the API token. User tokens are
+// verified with /user/tokens/verify, account owned tokens with the account's endpoint.
+func (c *Client) VerifyToken(ctx context.Context) (TokenStatus, error) {
+	var status TokenStatus
+	req, err := c.newRequest(ctx, http.MethodGet, strings.TrimRight(c.BaseURL, "/")+"/user/tokens/verify", nil)
+	if err != nil {
+		return status, err
+	}
+	userErr := c.call(req, &status)
+	if userErr == nil {
+		return status, nil
+	}
+
+	// Fall back to the endpoint for account owned tokens
+	req, err = c.newRequest(ctx, http.MethodGet, c.accountURL("tokens/verify"), nil)
+	if err != nil {
+		return status, err
+	}
+	if err := c.call(req, &status); err != nil {
+		return status, userErr
+	}
+	return status, nil
+}
+
+// Verify checks that the token is active and has Workers AI read and edit access
+func (c *Client) Verify(ctx context.Context) Verification {
+	var v Verification
+	v.Token, v.TokenErr = c.VerifyToken(ctx)
+
+	// Listing the models needs read access; a single model is enough to tell
+	var models []struct{}
+	_, _, v.ReadErr = c.ListModels(ctx, url.Values{"per_page": {"1"}}, "", &models)
+
+	// Running a model needs edit access; a tiny embedding keeps the cost negligible.
+	// The fixed input needs no validation, which would only cost another request.
+	v.EditErr = c.run(ctx, VerifyModel, map[string]any{"text": "ping"}, nil)
+	return v
+}
+
+// Err returns the first problem found, or nil if the credentials are fully usable
+func (v Verification) Err() error {
+	switch {
+	case v.TokenErr != nil:
+		return fmt.Errorf("token verification failed: %w", v.TokenErr)
+	case v.Token.Status != "active":
+		return fmt.Errorf("token is %s", v.Token.Status)
+	case v.ReadErr != nil:
+		return fmt.Errorf("token lacks Workers AI read access: %w", v.ReadErr)
+	case v.EditErr != nil:
+		return fmt.Errorf("token lacks Workers AI edit access: %w", v.EditErr)
+	}
+	return nil
+}
+
+// Report describes the verification result in a few human readable lines
+func (v Verification) Report() string {
+	var b strings.Builder
+	check := func(err error) string {
+		if err != nil {
+			return "missing (" + err.Error() + ")"
+		}
+		return "ok"
+	}
+
+	if v.TokenErr != nil {
+		fmt.Fprintf(&b, "Token:           invalid (%v)\n", v.TokenErr)
+	} else {
+		fmt.Fprintf(&b, "Token:           %s (ID %s)\n", v.Token.Status, v.Token.ID)
+		expires := v.Token.ExpiresOn
+		if expires == "" {
+			expires = "never"
+		}
+		fmt.Fprintf(&b, "Expires:         %s\n", expires)
+		if v.Token.NotBefore != "" {
+			fmt.Fprintf(&b, "Valid from:      %s\n", v.Token.NotBefore)
+		}
+	}
+	fmt.Fprintf(&b, "Workers AI read: %s\n", check(v.ReadErr))
+	fmt.Fprintf(&b, "Workers AI edit: %s\n", check(v.EditErr))
+	return b.String()
+}
+
+// LoadOrSetup creates a Client for the active profile, letting the user set up the
+// credentials first if none are configured yet, see auth.LoadOrSetup. New credentials
+// are verified before they are saved.
+func LoadOrSetup(ctx context.Context) (*Client, error) {
+	config, err := auth.LoadOrSetup(func(config auth.Config) (string, error) {
+		v := New(config).Verify(ctx)
+		return v.Report(), v.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return New(config), nil
+}
@@ -1,12 +1,25 @@
 package main
 
 import (
+	"MidAI/auth"
 	genimg "MidAI/cap/image"
 	gentext "MidAI/cap/text"
+	"MidAI/cli"
+	"MidAI/session"
 	"fmt"
+	"os"
 )
 
 func main() {
+	// Move files written by older versions to their current locations
+	auth.Migrate()
+	session.Migrate()
+
+	// Run the requested subcommand when arguments are given
+	if len(os.Args) > 1 {
+		os.Exit(cli.Run(os.Args[1:]))
+	}
+
 	var choice int
 	// Start the conversation
 	fmt.Printf("select your Generative AI type:\n1. Text\n2. Image\n")
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"MidAI/client"
+	"MidAI/paths"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// catalogCache is the on-disk form of a cached model catalog
+type catalogCache struct {
+	FetchedAt time.Time `json:"fetched_at"`     // When the catalog was last fetched or confirmed unchanged
+	ETag      string    `json:"etag,omitempty"` // ETag the API reported for the catalog, used to revalidate it
+	Models    []Model   `json:"models"`         // The cached catalog
+}
+
+// cacheFile returns the path of the catalog cache of the client's account, as the
+// models available may differ between accounts. Filtered catalogs are cached
+// separately, named after a hash of the query.
+func cacheFile(c *client.Client, query url.Values) string {
+	name := "models-" + c.Config.AccountID
+
+	// Catalogs of other API servers, such as a local stand-in, are kept apart
+	key := query.Encode()
+	if c.BaseURL != client.DefaultBaseURL {
+		key = c.BaseURL + "?" + key
+	}
+	if key != "" {
+		sum := sha256.Sum256([]byte(key))
+		name += "-" + hex.EncodeToString(sum[:4])
+	}
+	return filepath.Join(paths.CacheDir, name+".json")
+}
+
+// readCache reads a cached catalog, returning nil if there is none or it can't be read
+func readCache(file string) *catalogCache {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	var cache catalogCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil // A corrupt cache is simply fetched again
+	}
+	return &cache
+}
+
+// writeCache saves a catalog to the cache. The file is replaced atomically
+// so a concurrent run never reads a partially written cache.
+func writeCache(file string, cache catalogCache) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return fmt.Errorf("failed to encode model cache: %w", err)
+	}
+
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write model cache: %w", err)
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write model cache: %w", err)
+	}
+	return nil
+}
@@ -1,171 +1,272 @@
 package model
 
 import (
-	"MidAI/auth"
-	"encoding/json"
+	"MidAI/client"
+	"MidAI/settings"
+	"MidAI/table"
+	"context"
+	"errors"
 	"fmt"
-	"net/http"
+	"io"
+	"net/url"
+	"os"
 	"path"
+	"strconv"
 	"strings"
-	"syscall"
-
-	"golang.org/x/term"
+	"time"
 )
 
 // Model struct to hold model data with ID, Name, and Description fields
 type Model struct {
 	ID          string `json:"id"`
+	Source      int    `json:"source"` // ID of the source the model comes from
 	Name        string `json:"name"`
 	Description string `json:"description"`
 	Task        struct {
-		Capability string `json:"name"`
+		ID          string `json:"id"`
+		Capability  string `json:"name"`
+		Description string `json:"description"`
 	} `json:"task"`
+	Properties []Property `json:"properties"`
 }
 
-// ModelsResponse struct to hold the response from the Cloudflare API
-type ModelsResponse struct {
-	Success bool    `json:"success"`
-	Result  []Model `json:"result"`
+// ListOptions configures how GetAvailableModels retrieves the model catalog.
+// The filters are applied by the API; zero values don't filter.
+type ListOptions struct {
+	Search           string // Text to search for in the model names and descriptions
+	Task             string // Task of the models, e.g. "Text Generation" or "Text-to-Image"
+	Author           string // Author of the models, e.g. "meta"
+	Source           int    // ID of the source the models come from
+	HideExperimental bool   // Leave out experimental models
+	Refresh          bool   // Fetch the catalog even if the cached one is still fresh
 }
 
-// GetAvailableModels fetches available models from the Cloudflare API
-func GetAvailableModels(config auth.Config) ([]Model, error) {
-	// Construct the API URL using the account ID from the config
-	url := fmt.Sprintf("https://api.cloudflare.com/client/v4/accounts/%s/ai/models/search", config.AccountID)
+// perPage is the number of models requested per page of the catalog
+const perPage = 100
 
-	// Create a new HTTP GET request
-	request, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+// query returns the query parameters of the filters in opts
+func (opts ListOptions) query() url.Values {
+	query := url.Values{}
+	if opts.Search != "" {
+		query.Set("search", opts.Search)
 	}
-
-	// Set the authorization header using the API token from the config
-	request.Header.Set("Authorization", fmt.Sprintf("Bearer %s", config.Token))
-	client := &http.Client{}
-
-	// Execute the request
-	response, err := client.Do(request)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+	if opts.Task != "" {
+		query.Set("task", opts.Task)
+	}
+	if opts.Author != "" {
+		query.Set("author", opts.Author)
 	}
-	defer response.Body.Close()
+	if opts.Source != 0 {
+		query.Set("source", strconv.Itoa(opts.Source))
+	}
+	if opts.HideExperimental {
+		query.Set("hide_experimental", "true")
+	}
+	return query
+}
 
-	// Check if the response status is OK (200)
-	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected response status: %s", response.Status)
+// GetAvailableModels returns the models available from the Cloudflare API.
+// The catalog is cached on disk for the models_cache_ttl setting. Once it expires,
+// it is revalidated with its ETag, and if fetching it fails the stale cache is
+// used with a warning, unless opts.Refresh forces a fetch.
+func GetAvailableModels(ctx context.Context, c *client.Client, opts ListOptions) ([]Model, error) {
+	// Serve the cached catalog while it's fresh
+	ttl := settings.Duration(settings.ModelsCacheTTL)
+	query := opts.query()
+	file := cacheFile(c, query)
+	var cached *catalogCache
+	if ttl > 0 && !opts.Refresh {
+		cached = readCache(file)
+	}
+	if cached != nil && time.Since(cached.FetchedAt) < ttl {
+		return cached.Models, nil
 	}
 
-	// Decode the JSON response into the ModelsResponse struct
-	var modelsResponse ModelsResponse
-	if err = json.NewDecoder(response.Body).Decode(&modelsResponse); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	// Fetch the catalog, unless the API confirms the cached one is unchanged
+	var etag string
+	if cached != nil {
+		etag = cached.ETag
+	}
+	models, newETag, err := fetchModels(ctx, c, query, etag)
+	switch {
+	case errors.Is(err, client.ErrNotModified):
+		models, newETag = cached.Models, etag
+	case err != nil && cached != nil:
+		fmt.Fprintf(os.Stderr, "Warning: %v\nUsing the model catalog cached %s ago.\n",
+			err, time.Since(cached.FetchedAt).Round(time.Minute))
+		return cached.Models, nil
+	case err != nil:
+		return nil, err
 	}
 
-	// Check if the API response indicates success
-	if !modelsResponse.Success {
-		return nil, fmt.Errorf("failed to fetch models")
+	// Remember the catalog for the next runs; failing to do so only costs a fetch
+	if ttl > 0 {
+		if err := writeCache(file, catalogCache{FetchedAt: time.Now(), ETag: newETag, Models: models}); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
 	}
 
 	// Return the list of models from the response
-	return modelsResponse.Result, nil
+	return models, nil
 }
 
-// Function to determine the terminal width dynamically
-func getTerminalWidth() int {
-	width, _, err := term.GetSize(int(syscall.Stdout))
-	if err != nil {
-		return 120 // Fallback width if terminal size can't be determined
+// fetchModels collects all pages of the catalog matching query. The catalog is
+// revalidated with etag, which only covers it if it fits on a single page, so an
+// ETag is only returned in that case.
+func fetchModels(ctx context.Context, c *client.Client, query url.Values, etag string) ([]Model, string, error) {
+	var models []Model
+	for page := 1; ; page++ {
+		query.Set("page", strconv.Itoa(page))
+		query.Set("per_page", strconv.Itoa(perPage))
+
+		// Only the first page is requested conditionally
+		pageETag := ""
+		if page == 1 {
+			pageETag = etag
+		}
+		var pageModels []Model
+		info, newETag, err := c.ListModels(ctx, query, pageETag, &pageModels)
+		if err != nil {
+			if errors.Is(err, client.ErrNotModified) {
+				return nil, "", err
+			}
+			return nil, "", fmt.Errorf("failed to fetch models (page %d): %w", page, err)
+		}
+		models = append(models, pageModels...)
+
+		// Stop after the last page; without pagination details the result is
+		// complete, and a total count of 0 means the API didn't report one
+		if len(pageModels) == 0 || info.PerPage == 0 || len(pageModels) < info.PerPage || (info.TotalCount > 0 && len(models) >= info.TotalCount) {
+			if page > 1 {
+				newETag = ""
+			}
+			return models, newETag, nil
+		}
 	}
-	return width
 }
 
-// PrintModelsTable prints the list of models in a table format
+// PrintModelsTable prints the list of models in a table format, numbered for SelectModel.
+// The columns are sized to the terminal, wrapping the descriptions.
 func PrintModelsTable(models []Model) {
 	// Check if the models slice is nil or empty
-	if models == nil {
+	if len(models) == 0 {
 		fmt.Println("No models available to display.")
 		return
 	}
 
-	// Create a strings.Builder to build the table output
-	builder := &strings.Builder{}
-	// Print the table header
-	fmt.Fprintln(builder, "+----+---------------------------------------------+---------------------+----------------------------------------------------------------------------------------+")
-	fmt.Fprintln(builder, "| #  |                  Model Name                 |     Model Type      |                                      Description                                       |")
-	fmt.Fprintln(builder, "+----+---------------------------------------------+---------------------+----------------------------------------------------------------------------------------+")
-
-	// Iterate over the models and print each row
+	t := table.New(
+		table.Column{Header: "#", AlignRight: true},
+		table.Column{Header: "Model Name", Max: 45},
+		table.Column{Header: "Model Type", Max: 25},
+		table.Column{Header: "Description", Wrap: true},
+	)
+	t.Borders = true
 	for i, model := range models {
-		printRow(builder, i+1, model.Name, model.Task.Capability, model.Description)
-		// Print the table footer
-		fmt.Fprintln(builder, "+----+---------------------------------------------+---------------------+----------------------------------------------------------------------------------------+")
-
+		t.Append(strconv.Itoa(i+1), path.Base(model.Name), model.Task.Capability, model.Description)
 	}
-	// Output the entire table
-	fmt.Print(builder.String())
+	t.Print()
 }
 
-// printRow handles printing a single row of the table, truncating the description if necessary
-func printRow(builder *strings.Builder, number int, name string, capability string, description string) {
-	// Define fixed column widths
-	idWidth := 3          // ID column width (fixed)
-	nameWidth := 30       // Name column width (fixed)
-	capabilityWidth := 25 // Capability column width (fixed)
+// SelectModel lets the user pick one of models, which must be listed in the order
+// PrintModelsTable printed them: either by its number in the table or by its name,
+// see MatchModels. If several models match the name, the user picks one of those.
+func SelectModel(models []Model) (Model, error) {
+	// Check if models slice is nil or empty
+	if len(models) == 0 {
+		return Model{}, fmt.Errorf("no models available for selection")
+	}
 
-	// Dynamically calculate the remaining space for description
-	descriptionWidth := getTerminalWidth() - (idWidth + nameWidth + capabilityWidth + 16) // 16 accounts for column dividers
+	// Prompt the user to enter the model number or name
+	fmt.Print("Enter the number or the name of the model you'd like to use: ")
+	input, err := readLine()
+	if err != nil || input == "" {
+		return Model{}, fmt.Errorf("invalid selection")
+	}
 
-	// Ensure description width is not negative
-	if descriptionWidth < 16 {
-		descriptionWidth = 16 // Minimum width to prevent overflow
+	// A number picks the model in that row of the table
+	if number, err := strconv.Atoi(input); err == nil {
+		if number < 1 || number > len(models) {
+			return Model{}, fmt.Errorf("model number %d is not in the list", number)
+		}
+		return models[number-1], nil
 	}
 
-	// Trim and truncate fields
-	truncatedName := truncate(strings.TrimSpace(path.Base(name)), nameWidth)
-	truncatedCapability := truncate(strings.TrimSpace(capability), capabilityWidth)
-	truncatedDescription := truncate(strings.TrimSpace(description), descriptionWidth)
+	// Anything else is matched against the model names
+	matches := MatchModels(models, input)
+	switch len(matches) {
+	case 0:
+		return Model{}, fmt.Errorf("no model in the list matches %q", input)
+	case 1:
+		return matches[0], nil
+	}
 
-	// Print the formatted row to the builder
-	fmt.Fprintf(builder, "| %-*d | %-*s | %-*s | %-*s |\n",
-		idWidth, number,
-		nameWidth, truncatedName,
-		capabilityWidth, truncatedCapability,
-		descriptionWidth, truncatedDescription)
+	// Let the user narrow down the matching models
+	fmt.Printf("\nSeveral models match %q:\n", input)
+	PrintModelsTable(matches)
+	return SelectModel(matches)
 }
 
-// truncate ensures that text is cut off with '...' if it's too long
-func truncate(str string, maxLength int) string {
-	// Check if the string length exceeds the specified max length
-	if len(str) > maxLength {
-		// Return the truncated string with '...' at the end
-		return str[:maxLength-3] + "..."
+// WithTask returns the models of models that perform task, e.g. "Text Generation"
+func WithTask(models []Model, task string) []Model {
+	var filtered []Model
+	for _, m := range models {
+		if m.Task.Capability == task {
+			filtered = append(filtered, m)
+		}
 	}
-	// Return the original string if no truncation is needed
-	return str
+	return filtered
 }
 
-// SelectModel handles model selection based on user input
-func SelectModel(models []Model) (Model, error) {
-	// Check if models slice is nil or empty
-	if models == nil {
-		return Model{}, fmt.Errorf("no models available for selection")
+// PickModel returns the model performing task that matches name, letting the user
+// choose if several match. Without a name, the user picks from a table of the models
+// performing task. A full model ID such as "@cf/meta/llama-3-8b-instruct" is accepted
+// even if the catalog doesn't list it, but not a model listed with a different task.
+func PickModel(models []Model, task, name string) (Model, error) {
+	candidates := WithTask(models, task)
+	if name == "" {
+		PrintModelsTable(candidates)
+		return SelectModel(candidates)
 	}
 
-	var selectedModelIndex int
-	// Prompt the user to enter the model number
-	fmt.Print("Enter the number corresponding to the model you'd like to use: ")
-	_, err := fmt.Scanln(&selectedModelIndex)
-
-	// Validate the user input
-	if err != nil || selectedModelIndex < 1 || selectedModelIndex > len(models) {
-		return Model{}, fmt.Errorf("invalid selection")
+	matches := MatchModels(candidates, name)
+	switch len(matches) {
+	case 0:
+		// Tell apart a model of another task from one that doesn't exist
+		if others := MatchModels(models, name); len(others) > 0 {
+			return Model{}, fmt.Errorf("%s is a %q model, not a %q model", others[0].Name, others[0].Task.Capability, task)
+		}
+		if strings.HasPrefix(name, "@") {
+			return Model{Name: name}, nil // The catalog may not list every model
+		}
+		return Model{}, fmt.Errorf("no %q model matches %q", task, name)
+	case 1:
+		return matches[0], nil
 	}
 
-	// TODO: Add validation to prevent selecting a model that was not in the original list
-	if selectedModelIndex > len(models) {
-		return Model{}, fmt.Errorf("model number %d is not in the list", selectedModelIndex)
-	}
+	fmt.Printf("\nSeveral models match %q:\n", name)
+	PrintModelsTable(matches)
+	return SelectModel(matches)
+}
 
-	// Return the selected model based on the user's input
-	return models[selectedModelIndex-1], nil
+// readLine reads a line from stdin. Stdin is read a byte at a time so nothing
+// beyond the line is consumed, leaving the rest to later readers.
+func readLine() (string, error) {
+	var line []byte
+	buf := make([]byte, 1)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if n == 1 {
+			if buf[0] == '\n' {
+				break
+			}
+			line = append(line, buf[0])
+		}
+		if err == io.EOF && len(line) > 0 {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSpace(string(line)), nil
 }
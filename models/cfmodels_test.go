@@ -0,0 +1,58 @@
+package model
+
+import (
+	"MidAI/client"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+)
+
+// catalogHandler serves total models in pages of the requested size. The
+// total count is only reported if withTotal is set.
+func catalogHandler(total int, withTotal bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		size, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		var result []Model
+		for i := (page - 1) * size; i < min(page*size, total); i++ {
+			result = append(result, Model{Name: "@cf/test/model-" + strconv.Itoa(i)})
+		}
+		info := map[string]int{"page": page, "per_page": size, "count": len(result)}
+		if withTotal {
+			info["total_count"] = total
+		}
+		json.NewEncoder(w).Encode(map[string]any{"success": true, "result": result, "result_info": info})
+	}
+}
+
+func TestFetchModels(t *testing.T) {
+	tests := []struct {
+		name      string
+		total     int
+		withTotal bool
+	}{
+		{"total count", 2*perPage + 1, true},
+		{"no total count", 2*perPage + 1, false},
+		{"no total count, full last page", 2 * perPage, false},
+		{"single page", 3, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(catalogHandler(tt.total, tt.withTotal))
+			defer srv.Close()
+			c := &client.Client{BaseURL: srv.URL, HTTPClient: srv.Client()}
+
+			models, _, err := fetchModels(context.Background(), c, url.Values{}, "")
+			if err != nil {
+				t.Fatalf("fetchModels() error = %v", err)
+			}
+			if len(models) != tt.total {
+				t.Errorf("fetchModels() returned %d models, want %d", len(models), tt.total)
+			}
+		})
+	}
+}
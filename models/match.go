@@ -0,0 +1,105 @@
+package model
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// MatchModels returns the models whose name matches query, best matches first.
+// A full name such as "@cf/meta/llama-3.1-8b-instruct", or a name without its
+// "@cf/author/" prefix, matches only that model. Otherwise every model whose name
+// contains query is returned, and if there is none, the names containing the letters
+// and digits of query in order, e.g. "llama31 8b" matches "llama-3.1-8b-instruct".
+// Case is ignored except for full names.
+func MatchModels(models []Model, query string) []Model {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil
+	}
+
+	// A full or base name picks a single model
+	for _, m := range models {
+		if m.Name == query {
+			return []Model{m}
+		}
+	}
+	for _, m := range models {
+		if strings.EqualFold(path.Base(m.Name), query) {
+			return []Model{m}
+		}
+	}
+
+	// Look for the query within the names
+	lowerQuery := strings.ToLower(query)
+	var matches []Model
+	for _, m := range models {
+		if strings.Contains(strings.ToLower(m.Name), lowerQuery) {
+			matches = append(matches, m)
+		}
+	}
+	if len(matches) > 0 {
+		return matches
+	}
+
+	// Fall back to fuzzy matching, preferring names where the query is spread the least
+	normalizedQuery := normalizeName(query)
+	if normalizedQuery == "" {
+		return nil
+	}
+	type scoredModel struct {
+		model Model
+		score int
+	}
+	var scored []scoredModel
+	for _, m := range models {
+		if score := fuzzyScore(normalizeName(path.Base(m.Name)), normalizedQuery); score >= 0 {
+			scored = append(scored, scoredModel{m, score})
+		}
+	}
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].score < scored[j].score
+	})
+	for _, s := range scored {
+		matches = append(matches, s.model)
+	}
+	return matches
+}
+
+// normalizeName lowercases name and drops everything but letters and digits,
+// so "Llama-3.1 8B" becomes "llama318b"
+func normalizeName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// fuzzyScore reports how well name contains the characters of query in order:
+// the number of characters skipped between the first and the last matched one,
+// or -1 if query isn't contained in name at all
+func fuzzyScore(name, query string) int {
+	if strings.Contains(name, query) {
+		return 0 // Contiguous match
+	}
+
+	start, qi := -1, 0
+	q := []rune(query)
+	for i, r := range []rune(name) {
+		if r != q[qi] {
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+		qi++
+		if qi == len(q) {
+			return i - start + 1 - len(q)
+		}
+	}
+	return -1
+}
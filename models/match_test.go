@@ -0,0 +1,116 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+// testModel creates a catalog entry with the given name and task
+func testModel(name, task string) Model {
+	m := Model{Name: name}
+	m.Task.Capability = task
+	return m
+}
+
+// testCatalog is a small catalog mixing text generation and text-to-image models
+var testCatalog = []Model{
+	testModel("@cf/meta/llama-3.1-8b-instruct", "Text Generation"),
+	testModel("@cf/meta/llama-3.1-70b-instruct", "Text Generation"),
+	testModel("@cf/meta/llama-3-8b-instruct", "Text Generation"),
+	testModel("@hf/mistral/mistral-7b-instruct-v0.2", "Text Generation"),
+	testModel("@cf/black-forest-labs/flux-1-schnell", "Text-to-Image"),
+}
+
+// names returns the names of models separated by commas
+func names(models []Model) string {
+	n := make([]string, len(models))
+	for i, m := range models {
+		n[i] = m.Name
+	}
+	return strings.Join(n, ",")
+}
+
+func TestMatchModels(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"@cf/meta/llama-3-8b-instruct", "@cf/meta/llama-3-8b-instruct"},
+		{"LLAMA-3-8B-INSTRUCT", "@cf/meta/llama-3-8b-instruct"},
+		{"  mistral  ", "@hf/mistral/mistral-7b-instruct-v0.2"},
+		{"llama-3.1", "@cf/meta/llama-3.1-8b-instruct,@cf/meta/llama-3.1-70b-instruct"},
+		{"llama31 70b", "@cf/meta/llama-3.1-70b-instruct"},
+		{"l318b", "@cf/meta/llama-3.1-8b-instruct"},
+		{"flux schnell", "@cf/black-forest-labs/flux-1-schnell"},
+		{"gpt", ""},
+		{"", ""},
+		{"-.-", ""},
+	}
+	for _, tt := range tests {
+		if got := names(MatchModels(testCatalog, tt.query)); got != tt.want {
+			t.Errorf("MatchModels(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestMatchModelsFuzzyOrder(t *testing.T) {
+	// "llama38b" is spread the least over llama-3-8b, then over llama-3.1-8b
+	got := names(MatchModels(testCatalog, "llama 3 8b"))
+	want := "@cf/meta/llama-3-8b-instruct,@cf/meta/llama-3.1-8b-instruct"
+	if !strings.HasPrefix(got, want) {
+		t.Errorf("MatchModels(%q) = %q, want it to start with %q", "llama 3 8b", got, want)
+	}
+}
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		name, query string
+		want        int
+	}{
+		{"llama318binstruct", "llama318b", 0},
+		{"llama318binstruct", "llama38b", 1},
+		{"llama3170binstruct", "llama370b", 1},
+		{"llama318binstruct", "instruct", 0},
+		{"llama318binstruct", "mistral", -1},
+		{"llama", "llamas", -1},
+		{"", "a", -1},
+	}
+	for _, tt := range tests {
+		if got := fuzzyScore(tt.name, tt.query); got != tt.want {
+			t.Errorf("fuzzyScore(%q, %q) = %d, want %d", tt.name, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	if got := normalizeName("Llama-3.1 8B_Instruct"); got != "llama318binstruct" {
+		t.Errorf("normalizeName() = %q, want %q", got, "llama318binstruct")
+	}
+}
+
+func TestPickModel(t *testing.T) {
+	tests := []struct {
+		name    string
+		task    string
+		want    string
+		wantErr string
+	}{
+		{name: "llama31 70b", task: "Text Generation", want: "@cf/meta/llama-3.1-70b-instruct"},
+		{name: "@cf/meta/llama-4-unlisted", task: "Text Generation", want: "@cf/meta/llama-4-unlisted"},
+		{name: "lama-typo-9000", task: "Text Generation", wantErr: "no \"Text Generation\" model matches"},
+		{name: "flux-1-schnell", task: "Text Generation", wantErr: "is a \"Text-to-Image\" model"},
+		{name: "@cf/black-forest-labs/flux-1-schnell", task: "Text Generation", wantErr: "is a \"Text-to-Image\" model"},
+		{name: "flux", task: "Text-to-Image", want: "@cf/black-forest-labs/flux-1-schnell"},
+	}
+	for _, tt := range tests {
+		got, err := PickModel(testCatalog, tt.task, tt.name)
+		switch {
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("PickModel(%q) error = %v, want one containing %q", tt.name, err, tt.wantErr)
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("PickModel(%q) error = %v", tt.name, err)
+		case got.Name != tt.want:
+			t.Errorf("PickModel(%q) = %q, want %q", tt.name, got.Name, tt.want)
+		}
+	}
+}
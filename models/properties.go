@@ -0,0 +1,104 @@
+package model
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Property is a single entry of a model's "properties" list, such as its context window
+type Property struct {
+	PropertyID string          `json:"property_id"`
+	Value      json.RawMessage `json:"value"`
+}
+
+// String returns the property's value for display: strings without quotes, other values as JSON
+func (p Property) String() string {
+	var str string
+	if err := json.Unmarshal(p.Value, &str); err == nil {
+		return str
+	}
+	return string(p.Value)
+}
+
+// Price is an entry of a model's "price" property
+type Price struct {
+	Unit     string  `json:"unit"`     // What is billed, e.g. "per M input tokens"
+	Price    float64 `json:"price"`    // Price per unit
+	Currency string  `json:"currency"` // Currency of the price, e.g. "USD"
+}
+
+// Property returns the value of the property with the given ID, if the catalog reports it
+func (m Model) Property(id string) (json.RawMessage, bool) {
+	for _, p := range m.Properties {
+		if p.PropertyID == id {
+			return p.Value, true
+		}
+	}
+	return nil, false
+}
+
+// ContextWindow returns the model's context window in tokens, or 0 if the catalog doesn't report one
+func (m Model) ContextWindow() int {
+	value, ok := m.Property("context_window")
+	if !ok {
+		return 0
+	}
+
+	// The value is reported either as a number or as a string holding one
+	var n json.Number
+	if err := json.Unmarshal(value, &n); err != nil {
+		var str string
+		if err := json.Unmarshal(value, &str); err != nil {
+			return 0
+		}
+		n = json.Number(str)
+	}
+	window, err := n.Int64()
+	if err != nil {
+		return 0
+	}
+	return int(window)
+}
+
+// Beta reports whether the model is in beta
+func (m Model) Beta() bool {
+	return m.boolProperty("beta")
+}
+
+// LoRA reports whether the model can be run with LoRA adapters
+func (m Model) LoRA() bool {
+	return m.boolProperty("lora")
+}
+
+// Prices returns the model's pricing, or nil if the catalog doesn't report it
+func (m Model) Prices() []Price {
+	value, ok := m.Property("price")
+	if !ok {
+		return nil
+	}
+	var prices []Price
+	if err := json.Unmarshal(value, &prices); err != nil {
+		return nil
+	}
+	return prices
+}
+
+// boolProperty returns the value of a flag property, which is reported
+// either as a boolean or as a string holding one
+func (m Model) boolProperty(id string) bool {
+	value, ok := m.Property(id)
+	if !ok {
+		return false
+	}
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b
+	}
+	var str string
+	if err := json.Unmarshal(value, &str); err != nil {
+		return false
+	}
+	b, _ = strconv.ParseBool(strings.TrimSpace(str))
+	return b
+}
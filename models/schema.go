@@ -0,0 +1,23 @@
+package model
+
+import (
+	"MidAI/client"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Schema holds the JSON schemas describing a model's input and output
+type Schema struct {
+	Input  json.RawMessage `json:"input"`  // JSON schema of the request body
+	Output json.RawMessage `json:"output"` // JSON schema of the result
+}
+
+// GetSchema fetches the input and output schema of the named model
+func GetSchema(ctx context.Context, c *client.Client, name string) (Schema, error) {
+	var schema Schema
+	if err := c.ModelSchema(ctx, name, &schema); err != nil {
+		return Schema{}, fmt.Errorf("failed to fetch the schema of %s: %w", name, err)
+	}
+	return schema, nil
+}
@@ -0,0 +1,126 @@
+package paths
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+var Home string      // User's home directory path
+var ConfigDir string // Directory holding the configuration files
+var DataDir string   // Directory holding sessions and generated images
+var CacheDir string  // Directory holding cached data such as the model catalog
+
+// init is automatically called when the package is initialized.
+// It sets up the MidAI directories based on the OS:
+//
+//   - MIDAI_HOME, if set, holds everything, with the cache in its "cache" subdirectory
+//   - on Windows, %APPDATA%\midai holds the configuration and data, %LOCALAPPDATA%\midai the cache
+//   - elsewhere, the XDG base directories are used: $XDG_CONFIG_HOME/midai, $XDG_DATA_HOME/midai
+//     and $XDG_CACHE_HOME/midai, defaulting to ~/.config, ~/.local/share and ~/.cache
+func init() {
+	var err error
+	// Determine the user's home directory based on the operating system
+	if runtime.GOOS == "windows" {
+		Home = os.Getenv("USERPROFILE") // Get home directory on Windows
+	} else {
+		Home, err = os.UserHomeDir() // Get home directory on Unix-like systems
+		if err != nil {
+			panic(err) // Panic if unable to get home directory
+		}
+	}
+
+	if midaiHome := os.Getenv("MIDAI_HOME"); midaiHome != "" {
+		ConfigDir = midaiHome
+		DataDir = midaiHome
+		CacheDir = filepath.Join(midaiHome, "cache")
+		return
+	}
+
+	if runtime.GOOS == "windows" {
+		appData := envOr("APPDATA", filepath.Join(Home, "AppData", "Roaming"))
+		localAppData := envOr("LOCALAPPDATA", filepath.Join(Home, "AppData", "Local"))
+		ConfigDir = filepath.Join(appData, "midai")
+		DataDir = filepath.Join(appData, "midai")
+		CacheDir = filepath.Join(localAppData, "midai")
+		return
+	}
+
+	ConfigDir = filepath.Join(xdgDir("XDG_CONFIG_HOME", ".config"), "midai")
+	DataDir = filepath.Join(xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share")), "midai")
+	CacheDir = filepath.Join(xdgDir("XDG_CACHE_HOME", ".cache"), "midai")
+}
+
+// envOr returns the value of the environment variable key, or fallback if it's unset
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// xdgDir returns the XDG base directory named by key. Relative paths are invalid
+// according to the specification and ignored in favour of fallback in the home directory.
+func xdgDir(key, fallback string) string {
+	if value := os.Getenv(key); filepath.IsAbs(value) {
+		return value
+	}
+	return filepath.Join(Home, fallback)
+}
+
+// MigrateLegacy moves a file or directory from a legacy location to its current one,
+// unless nothing exists at oldPath or something already exists at newPath.
+// It reports whether anything was moved.
+func MigrateLegacy(oldPath, newPath string) (bool, error) {
+	if _, err := os.Stat(oldPath); err != nil {
+		return false, nil // Nothing to migrate
+	}
+	if _, err := os.Lstat(newPath); !errors.Is(err, os.ErrNotExist) {
+		return false, nil // Already migrated, or the new location is in use
+	}
+
+	if err := os.MkdirAll(filepath.Dir(newPath), 0700); err != nil {
+		return false, err
+	}
+
+	// Renaming fails across file systems, fall back to copying a file
+	if err := os.Rename(oldPath, newPath); err != nil {
+		if err := copyFile(oldPath, newPath); err != nil {
+			return false, fmt.Errorf("failed to move %s to %s: %w", oldPath, newPath, err)
+		}
+		if err := os.Remove(oldPath); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// copyFile copies a regular file, keeping its permissions
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%s is a directory", src)
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
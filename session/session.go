@@ -0,0 +1,228 @@
+package session
+
+import (
+	"MidAI/paths"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Message represents a single message in the conversation history
+type Message struct {
+	Role    string `json:"role"`    // Role of the message (system, user or assistant)
+	Content string `json:"content"` // Content of the message
+}
+
+// Session is a persisted chat conversation
+type Session struct {
+	ID           string    `json:"id"`                // Unique identifier, also the file name without extension
+	Model        string    `json:"model"`             // Name of the model the conversation is held with
+	SystemPrompt string    `json:"system_prompt"`     // System prompt sent ahead of the messages
+	Persona      string    `json:"persona,omitempty"` // Name of the persona the session was started with
+	Messages     []Message `json:"messages"`          // Full transcript of user and assistant messages
+	CreatedAt    time.Time `json:"created_at"`        // When the session was started
+	UpdatedAt    time.Time `json:"updated_at"`        // When the session was last saved
+}
+
+// Dir is the directory sessions are stored in
+var Dir string
+
+// init sets up the session directory in the MidAI data directory
+func init() {
+	Dir = filepath.Join(paths.DataDir, "sessions")
+}
+
+// Migrate moves the sessions saved by older versions to Dir. It is meant to be
+// called once at startup, before sessions are read.
+func Migrate() {
+	// Older versions kept the sessions in ~/.midai/sessions
+	legacyDir := filepath.Join(paths.Home, ".midai", "sessions")
+	if moved, err := paths.MigrateLegacy(legacyDir, Dir); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to migrate %s: %v\n", legacyDir, err)
+	} else if moved {
+		fmt.Fprintf(os.Stderr, "Moved sessions from %s to %s\n", legacyDir, Dir)
+		os.Remove(filepath.Dir(legacyDir)) // Remove ~/.midai if nothing else is left in it
+	}
+}
+
+// New creates an unsaved session for the given model and system prompt
+func New(model, systemPrompt string) *Session {
+	now := time.Now()
+	return &Session{
+		ID:           newID(now),
+		Model:        model,
+		SystemPrompt: systemPrompt,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+}
+
+// newID builds a sortable, human readable session ID such as "20250102-150405-a1b2"
+func newID(now time.Time) string {
+	suffix := make([]byte, 2)
+	if _, err := rand.Read(suffix); err != nil {
+		return now.Format("20060102-150405")
+	}
+	return now.Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// Title returns a short description of the session taken from its first user message
+func (s *Session) Title() string {
+	for _, m := range s.Messages {
+		if m.Role == "user" {
+			return strings.Join(strings.Fields(m.Content), " ")
+		}
+	}
+	return ""
+}
+
+// path returns the file a session with the given ID is stored in
+func path(id string) string {
+	return filepath.Join(Dir, id+".json")
+}
+
+// Save writes the session to the session directory and updates its timestamp
+func Save(s *Session) error {
+	// Create the session directory if it doesn't exist yet
+	if err := os.MkdirAll(Dir, 0700); err != nil {
+		return fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	s.UpdatedAt = time.Now()
+
+	// Convert the session to JSON
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a crash never leaves a truncated session behind
+	tmp := path(s.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path(s.ID))
+}
+
+// Load reads the session with the given ID. A unique ID prefix is accepted as well.
+func Load(id string) (*Session, error) {
+	id, err := resolve(id)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path(id))
+	if err != nil {
+		return nil, err
+	}
+
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse session %s: %w", id, err)
+	}
+	return &s, nil
+}
+
+// List returns all saved sessions, most recently updated first.
+// Session files that can't be read are skipped with a warning.
+func List() ([]Session, error) {
+	entries, err := os.ReadDir(Dir)
+	if err != nil {
+		// No session directory simply means no sessions have been saved yet
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var sessions []Session
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		s, err := Load(id)
+		if err != nil {
+			// A damaged file must not hide the other sessions
+			fmt.Fprintf(os.Stderr, "Warning: skipped an unreadable session: %v\n", err)
+			continue
+		}
+		sessions = append(sessions, *s)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].UpdatedAt.After(sessions[j].UpdatedAt)
+	})
+	return sessions, nil
+}
+
+// Delete removes the session with the given ID. A unique ID prefix is accepted as well.
+func Delete(id string) error {
+	id, err := resolve(id)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path(id))
+}
+
+// Prune removes all sessions that haven't been updated for longer than age
+// and returns the IDs of the removed sessions
+func Prune(age time.Duration) ([]string, error) {
+	sessions, err := List()
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	cutoff := time.Now().Add(-age)
+	for _, s := range sessions {
+		if s.UpdatedAt.Before(cutoff) {
+			if err := os.Remove(path(s.ID)); err != nil {
+				return removed, err
+			}
+			removed = append(removed, s.ID)
+		}
+	}
+	return removed, nil
+}
+
+// resolve maps a session ID or unique ID prefix to the full session ID
+func resolve(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\`) {
+		return "", fmt.Errorf("invalid session ID %q", id)
+	}
+
+	// An exact match wins
+	if _, err := os.Stat(path(id)); err == nil {
+		return id, nil
+	}
+
+	// Otherwise look for a unique prefix match
+	entries, err := os.ReadDir(Dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	var matches []string
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if ok && strings.HasPrefix(name, id) {
+			matches = append(matches, name)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("session %q not found", id)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("session ID %q is ambiguous: %s", id, strings.Join(matches, ", "))
+	}
+}
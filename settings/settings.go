@@ -0,0 +1,279 @@
+package settings
+
+import (
+	"MidAI/paths"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Keys of the available settings
+const (
+	TextModel        = "text_model"         // Default text generation model
+	ImageModel       = "image_model"        // Default text-to-image model
+	HistoryTokens    = "history_tokens"     // Token budget of the chat history, 0 to derive it from the context window
+	SystemPrompt     = "system_prompt"      // Default system prompt of chats
+	OutputDir        = "output_dir"         // Directory generated images are saved to
+	OutputFormat     = "output_format"      // Format of listings such as "models list"
+	ConnectTimeout   = "connect_timeout"    // Time allowed to connect to the API
+	ResponseTimeout  = "response_timeout"   // Time allowed for the API to start responding
+	RetryMaxAttempts = "retry_max_attempts" // Number of attempts of a failed request, including the first one
+	RetryMaxElapsed  = "retry_max_elapsed"  // Time after which a failed request isn't retried anymore
+	ModelsCacheTTL   = "models_cache_ttl"   // Time the cached model catalog is used without fetching it again
+	ValidateRequests = "validate_requests"  // Whether model inputs are checked against the model's schema
+	APIURL           = "api_url"            // Base URL of the Cloudflare API, e.g. of a local stand-in server
+)
+
+// DefaultAPIURL is the Cloudflare API endpoint used when api_url isn't changed
+const DefaultAPIURL = "https://api.cloudflare.com/client/v4"
+
+// Setting describes a setting: its key in the settings file, the environment
+// variable overriding it and its built-in default
+type Setting struct {
+	Key      string                   // Key in the settings file and of "midai config get/set"
+	Env      string                   // Environment variable overriding the settings file
+	Default  string                   // Value used when neither the environment nor the file sets one
+	Help     string                   // Short description shown by "midai config list"
+	validate func(value string) error // Checks a value before it's saved or used
+}
+
+// Sources a setting's value can come from, see Lookup
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+)
+
+// File is the path of the settings file
+var File string
+
+// Settings lists the available settings
+var Settings []Setting
+
+// init sets up the path of the settings file next to the credentials and the list of settings
+func init() {
+	File = filepath.Join(paths.ConfigDir, "settings.json")
+
+	Settings = []Setting{
+		{Key: TextModel, Help: "default text generation model", validate: anyValue},
+		{Key: ImageModel, Help: "default text-to-image model", validate: anyValue},
+		{Key: HistoryTokens, Default: "0", Help: "token budget of the chat history; 0 derives it from the model's context window", validate: nonNegativeInt},
+		{Key: SystemPrompt, Default: "You are a friendly assistant", Help: "default system prompt of chats", validate: anyValue},
+		{Key: OutputDir, Default: filepath.Join(paths.DataDir, "images"), Help: "directory generated images are saved to", validate: anyValue},
+		{Key: OutputFormat, Default: "table", Help: "format of listings: table, json, csv or yaml", validate: oneOf("table", "json", "csv", "yaml")},
+		{Key: ConnectTimeout, Default: "10s", Help: "time allowed to connect to the API", validate: positiveDuration},
+		{Key: ResponseTimeout, Default: "2m", Help: "time allowed for the API to start responding", validate: positiveDuration},
+		{Key: RetryMaxAttempts, Default: "3", Help: "attempts of a failed request, including the first one", validate: positiveInt},
+		{Key: RetryMaxElapsed, Default: "1m", Help: "time after which a failed request isn't retried anymore", validate: positiveDuration},
+		{Key: ValidateRequests, Default: "true", Help: "check model inputs against the model's input schema before sending them", validate: oneOf("true", "false")},
+		{Key: ModelsCacheTTL, Default: "24h", Help: "time the cached model catalog is used without fetching it again; 0 disables the cache", validate: nonNegativeDuration},
+		{Key: APIURL, Default: DefaultAPIURL, Help: "base URL of the Cloudflare API, e.g. of a local stand-in server", validate: httpURL},
+	}
+	for i := range Settings {
+		Settings[i].Env = "MIDAI_" + strings.ToUpper(Settings[i].Key)
+	}
+}
+
+// Find returns the setting with the given key
+func Find(key string) (Setting, error) {
+	key = strings.ReplaceAll(strings.TrimSpace(key), "-", "_") // Accept "text-model" as well
+	for _, s := range Settings {
+		if s.Key == key {
+			return s, nil
+		}
+	}
+	return Setting{}, fmt.Errorf("unknown setting %q", key)
+}
+
+// Validate checks that value is valid for the setting
+func (s Setting) Validate(value string) error {
+	if err := s.validate(value); err != nil {
+		return fmt.Errorf("invalid %s %q: %w", s.Key, value, err)
+	}
+	return nil
+}
+
+// LoadFile reads the settings file, returning an empty map if it doesn't exist.
+// Numbers and booleans are accepted as well as strings, as the file may be edited by hand.
+func LoadFile() (map[string]string, error) {
+	values := make(map[string]string)
+	data, err := os.ReadFile(File)
+	if errors.Is(err, os.ErrNotExist) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings file: %w", err)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse settings file %s: %w", File, err)
+	}
+	for key, value := range raw {
+		var s string
+		if err := json.Unmarshal(value, &s); err != nil {
+			s = string(value) // Keep numbers and booleans as written
+		}
+		values[key] = s
+	}
+	return values, nil
+}
+
+// SaveFile writes the settings file, creating the config directory if necessary
+func SaveFile(values map[string]string) error {
+	if err := os.MkdirAll(filepath.Dir(File), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode settings: %w", err)
+	}
+	if err := os.WriteFile(File, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write settings file: %w", err)
+	}
+
+	// Let the next lookup see the new values
+	loadOnce = sync.Once{}
+	return nil
+}
+
+// The settings file is read once per run by Lookup
+var (
+	loadOnce   sync.Once
+	fileValues map[string]string
+)
+
+// Lookup returns the value of the setting with the given key and where it comes from:
+// the environment wins over the settings file, which wins over the built-in default.
+// Invalid values are reported on stderr and skipped.
+func Lookup(key string) (string, string) {
+	s, err := Find(key)
+	if err != nil {
+		panic(err) // Keys are constants, an unknown one is a programming error
+	}
+
+	// Read the settings file on first use
+	loadOnce.Do(func() {
+		var err error
+		if fileValues, err = LoadFile(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	})
+
+	if value := os.Getenv(s.Env); value != "" {
+		if err := s.Validate(value); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring %s: %v\n", s.Env, err)
+		} else {
+			return value, SourceEnv
+		}
+	}
+	if value, ok := fileValues[s.Key]; ok {
+		if err := s.Validate(value); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring %s in %s: %v\n", s.Key, File, err)
+		} else {
+			return value, SourceFile
+		}
+	}
+	return s.Default, SourceDefault
+}
+
+// String returns the value of the setting with the given key, see Lookup
+func String(key string) string {
+	value, _ := Lookup(key)
+	return value
+}
+
+// Int returns the value of an integer setting, see Lookup
+func Int(key string) int {
+	n, _ := strconv.Atoi(String(key)) // Values are validated by Lookup
+	return n
+}
+
+// Duration returns the value of a duration setting, see Lookup
+func Duration(key string) time.Duration {
+	d, _ := time.ParseDuration(String(key)) // Values are validated by Lookup
+	return d
+}
+
+// UnknownKeys returns the keys of values that aren't settings, e.g. typos in the settings file
+func UnknownKeys(values map[string]string) []string {
+	var keys []string
+	for key := range values {
+		if _, err := Find(key); err != nil {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// anyValue accepts every value
+func anyValue(string) error {
+	return nil
+}
+
+// nonNegativeInt accepts integers of at least 0
+func nonNegativeInt(value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return errors.New("must be a whole number of at least 0")
+	}
+	return nil
+}
+
+// positiveInt accepts integers of at least 1
+func positiveInt(value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return errors.New("must be a whole number of at least 1")
+	}
+	return nil
+}
+
+// positiveDuration accepts durations such as "30s" or "2m" greater than zero
+func positiveDuration(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return errors.New(`must be a positive duration such as "30s" or "2m"`)
+	}
+	return nil
+}
+
+// nonNegativeDuration accepts durations such as "30s" or "24h" of at least zero
+func nonNegativeDuration(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return errors.New(`must be a duration such as "30s" or "24h", or 0`)
+	}
+	return nil
+}
+
+// httpURL accepts absolute http and https URLs
+func httpURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New(`must be an http or https URL such as "http://localhost:8787"`)
+	}
+	return nil
+}
+
+// oneOf accepts the given values
+func oneOf(allowed ...string) func(string) error {
+	return func(value string) error {
+		for _, a := range allowed {
+			if value == a {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(allowed, ", "))
+	}
+}
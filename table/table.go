@@ -0,0 +1,331 @@
+package table
+
+import (
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/term"
+	"golang.org/x/text/width"
+)
+
+// DefaultWidth is the table width used when the terminal width can't be determined
+const DefaultWidth = 120
+
+// minColumnWidth is the width columns are shrunk to at most when the table is too wide
+const minColumnWidth = 8
+
+// Column describes a column of a Table
+type Column struct {
+	Header     string // Title printed above the column; the header line is left out if all are empty
+	Max        int    // Maximum display width of the column, 0 for no limit
+	Wrap       bool   // Wrap long cells onto several lines instead of truncating them
+	AlignRight bool   // Align the cells to the right, e.g. for numbers
+}
+
+// Table renders rows of text as aligned columns. The columns are sized to their
+// content and shrunk to fit the terminal, measuring text by its display width,
+// so wide characters such as CJK take two cells and combining marks none.
+type Table struct {
+	Columns []Column   // Columns of the table
+	Rows    [][]string // Cells of the rows, one per column
+	Borders bool       // Draw borders around the cells instead of separating the columns by spaces
+	Width   int        // Maximum width of the table; the terminal width when 0
+	Indent  int        // Number of spaces every line is indented by
+}
+
+// New creates a table with the given columns
+func New(columns ...Column) *Table {
+	return &Table{Columns: columns}
+}
+
+// Append adds a row to the table
+func (t *Table) Append(cells ...string) {
+	t.Rows = append(t.Rows, cells)
+}
+
+// Print renders the table to stdout
+func (t *Table) Print() error {
+	return t.Render(os.Stdout)
+}
+
+// Render writes the table to w
+func (t *Table) Render(w io.Writer) error {
+	widths := t.columnWidths()
+	var b strings.Builder
+
+	// The header line is left out if no column has a title
+	hasHeader := false
+	for _, c := range t.Columns {
+		hasHeader = hasHeader || c.Header != ""
+	}
+
+	// Draw a rule between rows if cells may span several lines, so rows stay apart
+	wraps := false
+	for _, c := range t.Columns {
+		wraps = wraps || c.Wrap
+	}
+
+	t.writeRule(&b, widths)
+	if hasHeader {
+		headers := make([]string, len(t.Columns))
+		for i, c := range t.Columns {
+			headers[i] = c.Header
+		}
+		t.writeRow(&b, widths, headers)
+		t.writeRule(&b, widths)
+	}
+	for i, row := range t.Rows {
+		t.writeRow(&b, widths, row)
+		if wraps && i < len(t.Rows)-1 {
+			t.writeRule(&b, widths)
+		}
+	}
+	if len(t.Rows) > 0 {
+		t.writeRule(&b, widths)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// columnWidths sizes the columns to their widest cell, then shrinks the widest
+// columns until the table fits its width, preferring columns that wrap
+func (t *Table) columnWidths() []int {
+	widths := make([]int, len(t.Columns))
+	for i, c := range t.Columns {
+		widths[i] = Width(c.Header)
+		for _, row := range t.Rows {
+			if i < len(row) {
+				widths[i] = max(widths[i], Width(clean(row[i])))
+			}
+		}
+		if c.Max > 0 {
+			widths[i] = min(widths[i], c.Max)
+		}
+	}
+
+	// Space taken by the separators: "| " + " | " between cells + " |", or two spaces between cells
+	overhead := 2 * (len(widths) - 1)
+	if t.Borders {
+		overhead = 3*len(widths) + 1
+	}
+	available := t.Width
+	if available <= 0 {
+		available = TerminalWidth()
+	}
+	available -= overhead + t.Indent
+
+	for excess := sum(widths) - available; excess > 0; excess = sum(widths) - available {
+		// Pick the widest column that can still shrink, wrapping columns first
+		widest := -1
+		for i, w := range widths {
+			if w <= minColumnWidth {
+				continue
+			}
+			if widest < 0 || (t.Columns[i].Wrap && !t.Columns[widest].Wrap) ||
+				(t.Columns[i].Wrap == t.Columns[widest].Wrap && w > widths[widest]) {
+				widest = i
+			}
+		}
+		if widest < 0 {
+			break // Every column is as narrow as it gets, let the terminal wrap the lines
+		}
+		widths[widest] = max(minColumnWidth, widths[widest]-excess)
+	}
+	return widths
+}
+
+// writeRow writes a row, wrapping or truncating its cells to the column widths
+func (t *Table) writeRow(b *strings.Builder, widths []int, row []string) {
+	// Split the cells into the lines they occupy
+	lines := make([][]string, len(widths))
+	height := 1
+	for i := range widths {
+		cell := ""
+		if i < len(row) {
+			cell = clean(row[i])
+		}
+		if t.Columns[i].Wrap {
+			lines[i] = Wrap(cell, widths[i])
+		} else {
+			lines[i] = []string{Truncate(cell, widths[i])}
+		}
+		height = max(height, len(lines[i]))
+	}
+
+	for l := 0; l < height; l++ {
+		var line strings.Builder
+		line.WriteString(strings.Repeat(" ", t.Indent))
+		if t.Borders {
+			line.WriteString("| ")
+		}
+		for i, w := range widths {
+			if i > 0 {
+				if t.Borders {
+					line.WriteString(" | ")
+				} else {
+					line.WriteString("  ")
+				}
+			}
+			text := ""
+			if l < len(lines[i]) {
+				text = lines[i][l]
+			}
+			line.WriteString(pad(text, w, t.Columns[i].AlignRight))
+		}
+		if t.Borders {
+			line.WriteString(" |")
+			b.WriteString(line.String())
+		} else {
+			b.WriteString(strings.TrimRight(line.String(), " ")) // Trailing padding is only needed inside borders
+		}
+		b.WriteByte('\n')
+	}
+}
+
+// writeRule writes a horizontal border line such as "+----+------+"
+func (t *Table) writeRule(b *strings.Builder, widths []int) {
+	if !t.Borders {
+		return
+	}
+	b.WriteString(strings.Repeat(" ", t.Indent))
+	b.WriteByte('+')
+	for _, w := range widths {
+		b.WriteString(strings.Repeat("-", w+2))
+		b.WriteByte('+')
+	}
+	b.WriteByte('\n')
+}
+
+// TerminalWidth returns the width of the terminal stdout is connected to, or DefaultWidth
+func TerminalWidth() int {
+	w, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || w <= 0 {
+		return DefaultWidth // Fallback width if terminal size can't be determined
+	}
+	return w
+}
+
+// Width returns the number of terminal cells s occupies: two for East Asian wide
+// and fullwidth characters, none for combining marks and other invisible characters
+func Width(s string) int {
+	n := 0
+	for _, r := range s {
+		n += runeWidth(r)
+	}
+	return n
+}
+
+// runeWidth returns the number of terminal cells r occupies
+func runeWidth(r rune) int {
+	// Control characters, combining marks and format characters such as the zero width joiner
+	if unicode.IsControl(r) || unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	// Emoji skin tone modifiers merge into the preceding emoji
+	if r >= 0x1F3FB && r <= 0x1F3FF {
+		return 0
+	}
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	return 1
+}
+
+// Truncate shortens s to at most maxWidth cells, ending it with "..." if it was cut.
+// Characters are never split.
+func Truncate(s string, maxWidth int) string {
+	if Width(s) <= maxWidth {
+		return s
+	}
+	if maxWidth <= 3 {
+		return strings.Repeat(".", max(maxWidth, 0))
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range s {
+		w := runeWidth(r)
+		if n+w > maxWidth-3 {
+			break
+		}
+		b.WriteRune(r)
+		n += w
+	}
+	return b.String() + "..."
+}
+
+// Wrap breaks s into lines of at most maxWidth cells, breaking at spaces where
+// possible and within words that don't fit on a line of their own
+func Wrap(s string, maxWidth int) []string {
+	if maxWidth < 1 {
+		maxWidth = 1
+	}
+
+	var lines []string
+	var line strings.Builder
+	lineWidth := 0
+	flush := func() {
+		lines = append(lines, line.String())
+		line.Reset()
+		lineWidth = 0
+	}
+
+	for _, word := range strings.Fields(s) {
+		wordWidth := Width(word)
+
+		// Start a new line if the word doesn't fit behind the previous one
+		if lineWidth > 0 && lineWidth+1+wordWidth > maxWidth {
+			flush()
+		}
+		if lineWidth > 0 {
+			line.WriteByte(' ')
+			lineWidth++
+		}
+
+		// Break words longer than a line
+		for _, r := range word {
+			w := runeWidth(r)
+			if lineWidth+w > maxWidth && lineWidth > 0 {
+				flush()
+			}
+			line.WriteRune(r)
+			lineWidth += w
+		}
+	}
+	if lineWidth > 0 || len(lines) == 0 {
+		flush()
+	}
+	return lines
+}
+
+// pad fills s with spaces to w cells
+func pad(s string, w int, alignRight bool) string {
+	padding := strings.Repeat(" ", max(w-Width(s), 0))
+	if alignRight {
+		return padding + s
+	}
+	return s + padding
+}
+
+// clean replaces line breaks and tabs by spaces, as a cell must stay on its row
+func clean(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\r' || r == '\t' {
+			return ' '
+		}
+		return r
+	}, strings.TrimSpace(s))
+}
+
+// sum adds up widths
+func sum(widths []int) int {
+	total := 0
+	for _, w := range widths {
+		total += w
+	}
+	return total
+}
@@ -0,0 +1,160 @@
+package table
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWidth(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{"", 0},
+		{"abc", 3},
+		{"日本語", 6},           // CJK characters take two cells
+		{"ｆｕｌｌ", 8},          // Fullwidth forms as well
+		{"e\u0301", 1},       // A combining acute accent takes none
+		{"a\u200db", 2},      // Neither does the zero width joiner
+		{"👍\U0001F3FD", 2},   // A skin tone modifier merges into the emoji
+		{"tab\there", 7},     // Control characters take none
+		{"Ελληνικά", 8},      // Other scripts take one cell per letter
+		{"混合 mixed", 10},     // Mixed widths add up
+		{"\u0915\u094d", 1},  // Devanagari virama is a combining mark
+		{"\u3000", 2},        // Ideographic space is wide
+		{"\u00a0", 1},        // No-break space is narrow
+		{"\U0001F600abc", 5}, // Emoji are wide
+	}
+	for _, tt := range tests {
+		if got := Width(tt.s); got != tt.want {
+			t.Errorf("Width(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s        string
+		maxWidth int
+		want     string
+	}{
+		{"hello", 5, "hello"},
+		{"hello world", 8, "hello..."},
+		{"abcdef", 3, "..."},
+		{"abcdef", 2, ".."},
+		{"abcdef", 0, ""},
+		{"日本語テキスト", 7, "日本..."},                         // Wide characters are never split
+		{"日本語テキスト", 8, "日本..."},                         // Even if that leaves a cell free
+		{"日本語", 6, "日本語"},                               // Exactly fitting text is kept
+		{strings.Repeat("e\u0301", 5), 4, "e\u0301..."}, // Combining marks stay with their letter
+	}
+	for _, tt := range tests {
+		got := Truncate(tt.s, tt.maxWidth)
+		if got != tt.want {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tt.s, tt.maxWidth, got, tt.want)
+		}
+		if Width(got) > max(tt.maxWidth, 0) {
+			t.Errorf("Truncate(%q, %d) = %q is %d cells wide", tt.s, tt.maxWidth, got, Width(got))
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		s        string
+		maxWidth int
+		want     []string
+	}{
+		{"the quick brown fox", 10, []string{"the quick", "brown fox"}},
+		{"  spaced   out  ", 20, []string{"spaced out"}},
+		{"abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"日本語テキスト", 5, []string{"日本", "語テ", "キス", "ト"}},
+		{"日本 語", 4, []string{"日本", "語"}},
+		{"e\u0301te\u0301 cafe\u0301", 5, []string{"e\u0301te\u0301", "cafe\u0301"}}, // Combining marks take no cells
+		{"", 5, []string{""}},
+		{"a b", 0, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		got := Wrap(tt.s, tt.maxWidth)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("Wrap(%q, %d) = %q, want %q", tt.s, tt.maxWidth, got, tt.want)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	newTable := func() *Table {
+		t := New(Column{Header: "#", AlignRight: true}, Column{Header: "Name"})
+		t.Width = 80
+		t.Append("1", "日本")
+		t.Append("10", "abc")
+		return t
+	}
+
+	tests := []struct {
+		name  string
+		setup func(t *Table)
+		want  string
+	}{
+		{
+			name: "plain",
+			want: " #  Name\n" +
+				" 1  日本\n" +
+				"10  abc\n",
+		},
+		{
+			name:  "borders",
+			setup: func(t *Table) { t.Borders = true },
+			want: "+----+------+\n" +
+				"|  # | Name |\n" +
+				"+----+------+\n" +
+				"|  1 | 日本 |\n" +
+				"| 10 | abc  |\n" +
+				"+----+------+\n",
+		},
+		{
+			name:  "indent",
+			setup: func(t *Table) { t.Indent = 2 },
+			want: "   #  Name\n" +
+				"   1  日本\n" +
+				"  10  abc\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := newTable()
+			if tt.setup != nil {
+				tt.setup(table)
+			}
+			var b strings.Builder
+			if err := table.Render(&b); err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("Render() =\n%s\nwant\n%s", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderShrinksToWidth(t *testing.T) {
+	table := New(Column{Header: "ID"}, Column{Header: "Text", Wrap: true})
+	table.Width = 20
+	table.Borders = true
+	table.Append("a1", "the quick brown fox jumps")
+	table.Append("a2", "日本語のテキストです")
+
+	var b strings.Builder
+	if err := table.Render(&b); err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	for _, line := range lines {
+		if w := Width(line); w != Width(lines[0]) || w > 20 {
+			t.Errorf("line %q is %d cells wide, want %d and at most 20", line, w, Width(lines[0]))
+		}
+	}
+	if !strings.Contains(b.String(), "| a1 | the quick   |\n|    | brown fox   |\n") {
+		t.Errorf("Render() didn't wrap the text:\n%s", b.String())
+	}
+}